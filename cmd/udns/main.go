@@ -0,0 +1,296 @@
+// Command udns manages UltraDNS zones, records and Traffic Controller pools from the command line.
+//
+// Usage:
+//
+//	udns [flags] <command> <subcommand> [arguments]
+//
+// Credentials are read by ultradns.DefaultCredentials: from the ULTRADNS_USERNAME/ULTRADNS_PASSWORD or
+// ULTRADNS_REFRESH_TOKEN environment variables, or from a profile of ~/.ultradns/credentials. Run udns -h for the
+// commands, and the exit codes.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// Exit codes. Errors of the API are mapped by their apierror.Kind.
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitNotFound
+	exitAlreadyExists
+	exitUnauthorized
+	exitValidation
+	exitConflict
+	exitRateLimited
+	exitServerError
+	exitTaskFailed
+)
+
+const usage = `Usage: udns [flags] <command> <subcommand> [arguments]
+
+Commands:
+  status                                    check that the API is reachable and the credentials work
+  zone list [-q query]                      list zones
+  zone get <zone>                           show a zone
+  zone create [-account name] <zone>        create an empty primary zone (account default $ULTRADNS_ACCOUNT)
+  zone delete <zone>                        delete a zone and all of its records
+  record list [-type type] [-owner name] <zone>
+                                            list record sets
+  record add [-ttl seconds] <zone> <owner> <type> <rdata>...
+                                            add records, creating the record set if needed
+  record rm <zone> <owner> <type> [<rdata>...]
+                                            remove records, or the whole record set without rdata
+  record set [-ttl seconds] <zone> <owner> <type> <rdata>...
+                                            replace the records of a record set
+  pool list <zone>                          list Traffic Controller pools
+  pool get [-type type] <zone> <owner>      show the members of a pool
+  pool add [-type type] [-priority n] [-threshold n] [-state state] <zone> <owner> <rdata>
+                                            add a member to a pool
+  pool rm [-type type] <zone> <owner> <rdata>
+                                            remove a member from a pool
+  pool state [-type type] <zone> <owner> <rdata> <NORMAL|ACTIVE|INACTIVE>
+                                            set the state of a pool member
+  task get <id>                             show a task
+  task wait [-interval duration] <id>       wait for a task to finish
+
+Credentials are read from ULTRADNS_USERNAME/ULTRADNS_PASSWORD or ULTRADNS_REFRESH_TOKEN, or from a profile of
+~/.ultradns/credentials (see -profile).
+
+Exit codes:
+  0 success, 1 other errors, 2 usage errors, 3 not found, 4 already exists, 5 unauthorized, 6 validation failed,
+  7 conflict, 8 rate limited, 9 server error, 10 task failed
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// usageError is returned for invalid command lines.
+type usageError struct {
+	message string
+}
+
+// Error is the interface for the error type.
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// usagef returns a usageError.
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// cli holds the global options and the connection shared by the commands.
+type cli struct {
+	stdout  io.Writer
+	format  string
+	apiConn *ultradns.APIConnection
+}
+
+// command runs a subcommand with its arguments.
+type command func(ctx context.Context, c *cli, args []string) error
+
+// commands maps the commands to their subcommands.
+var commands = map[string]map[string]command{
+	"zone": {
+		"list":   zoneList,
+		"get":    zoneGet,
+		"create": zoneCreate,
+		"delete": zoneDelete,
+	},
+	"record": {
+		"list": recordList,
+		"add":  recordAdd,
+		"rm":   recordRemove,
+		"set":  recordSet,
+	},
+	"pool": {
+		"list":  poolList,
+		"get":   poolGet,
+		"add":   poolAdd,
+		"rm":    poolRemove,
+		"state": poolState,
+	},
+	"task": {
+		"get":  taskGet,
+		"wait": taskWait,
+	},
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("udns", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	profile := flags.String("profile", "", "Profile of ~/.ultradns/credentials to use (default $ULTRADNS_PROFILE or 'default')")
+	baseURL := flags.String("base-url", os.Getenv("ULTRADNS_BASE_URL"), "API endpoint (default $ULTRADNS_BASE_URL or https://api.ultradns.com)")
+	format := flags.String("o", "table", "Output format: table, json or yaml")
+	timeout := flags.Duration("timeout", time.Minute, "Time limit for the whole command")
+	wait := flags.Bool("wait", true, "Wait for changes that UltraDNS processes asynchronously, or print the ID of their task")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	switch *format {
+	case "table", "json", "yaml":
+	default:
+		fmt.Fprintf(stderr, "udns: unknown output format '%s'\n", *format)
+		return exitUsage
+	}
+
+	cmd, cmdArgs, err := lookup(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "udns: %s\n", err)
+		fmt.Fprintln(stderr, "Run 'udns -h' for usage.")
+		return exitUsage
+	}
+
+	c := &cli{
+		stdout: stdout,
+		format: *format,
+		apiConn: ultradns.NewAPIConnection(&ultradns.APIOptions{
+			Credentials:  ultradns.DefaultCredentials(*profile),
+			BaseURL:      *baseURL,
+			Timeout:      *timeout,
+			Retry:        &ultradns.RetryPolicy{},
+			WaitForTasks: *wait,
+		}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err = cmd(ctx, c, cmdArgs); err != nil {
+		fmt.Fprintf(stderr, "udns: %s\n", err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// lookup finds the command for the arguments and returns it with the remaining arguments.
+func lookup(args []string) (command, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, usagef("no command given")
+	}
+	if args[0] == "status" {
+		return status, args[1:], nil
+	}
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		return nil, nil, usagef("unknown command '%s'", args[0])
+	}
+	if len(args) < 2 {
+		return nil, nil, usagef("%s needs a subcommand: %s", args[0], strings.Join(names(subcommands), ", "))
+	}
+	cmd, ok := subcommands[args[1]]
+	if !ok {
+		return nil, nil, usagef("unknown subcommand '%s %s'", args[0], args[1])
+	}
+	return cmd, args[2:], nil
+}
+
+// exitCode maps an error to the exit code of the command.
+func exitCode(err error) int {
+	var usageErr *usageError
+	var taskErr *ultradns.TaskError
+	var authErr *ultradns.AuthorizationError
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &taskErr):
+		return exitTaskFailed
+	case errors.As(err, &authErr) || errors.Is(err, ultradns.ErrNoCredentials):
+		return exitUnauthorized
+	}
+
+	// Errors detected by the commands themselves wrap a bare Kind.
+	kind := apierror.KindOf(err)
+	if kind == apierror.Unknown {
+		errors.As(err, &kind)
+	}
+	switch kind {
+	case apierror.NotFound:
+		return exitNotFound
+	case apierror.AlreadyExists:
+		return exitAlreadyExists
+	case apierror.Unauthorized:
+		return exitUnauthorized
+	case apierror.Validation:
+		return exitValidation
+	case apierror.Conflict:
+		return exitConflict
+	case apierror.RateLimited:
+		return exitRateLimited
+	case apierror.ServerError:
+		return exitServerError
+	default:
+		return exitError
+	}
+}
+
+// status checks that the API is reachable and the credentials work.
+func status(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("status")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	result := struct {
+		Message string `json:"message"`
+	}{}
+	if err := c.apiConn.GetJSONContext(ctx, "/status", &result); err != nil {
+		return err
+	}
+	return c.print(result, &table{
+		header: []string{"STATUS"},
+		rows:   [][]string{{result.Message}},
+	})
+}
+
+// changed completes a command changing something. If UltraDNS processes the change asynchronously and -wait=false was
+// given, the ID of its task is printed for 'udns task wait'.
+func (c *cli) changed(taskID string, err error) error {
+	if err != nil || taskID == "" || c.apiConn.WaitForTasks {
+		return err
+	}
+	return c.print(map[string]string{"taskId": taskID}, &table{header: []string{"TASK"}, rows: [][]string{{taskID}}})
+}
+
+// newFlagSet returns the flag set of a subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	return flags
+}
+
+// parse parses the flags of a subcommand and checks that it got between min and max positional arguments. A
+// negative max allows any number.
+func parse(flags *flag.FlagSet, args []string, min int, max int) error {
+	if err := flags.Parse(args); err != nil {
+		return usagef("%s: %s", flags.Name(), err)
+	}
+	n := flags.NArg()
+	if n < min || (max >= 0 && n > max) {
+		return usagef("%s: wrong number of arguments; run 'udns -h' for usage", flags.Name())
+	}
+	return nil
+}
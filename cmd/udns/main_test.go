@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+	"github.com/simplifi/ultradns-go/pkg/ultradnstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// setCredentials points the credentials environment at the test server's user, returning a function restoring it.
+func setCredentials(username string, password string) func() {
+	values := map[string]string{
+		"ULTRADNS_USERNAME":      username,
+		"ULTRADNS_PASSWORD":      password,
+		"ULTRADNS_REFRESH_TOKEN": "",
+	}
+	saved := map[string]string{}
+	for key, value := range values {
+		saved[key] = os.Getenv(key)
+		os.Setenv(key, value)
+	}
+	return func() {
+		for key, value := range saved {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+// udns runs the command line against the server, returning the exit code and the output.
+func udns(server *ultradnstest.Server, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-base-url", server.URL}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newTestServer() (*ultradnstest.Server, func()) {
+	server := ultradnstest.NewServer()
+	restore := setCredentials(ultradnstest.Username, ultradnstest.Password)
+	return server, func() {
+		restore()
+		server.Close()
+	}
+}
+
+func TestStatus(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+
+	code, stdout, stderr := udns(server, "status")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "STATUS\nGood\n", stdout)
+}
+
+func TestUsageErrors(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+
+	for _, args := range [][]string{
+		{},
+		{"nope"},
+		{"zone"},
+		{"zone", "nope"},
+		{"zone", "get"},
+		{"zone", "get", "a.com", "b.com"},
+		{"record", "add", "example.com", "www", "NOPE", "1.2.3.4"},
+		{"-o", "xml", "status"},
+	} {
+		code, stdout, stderr := udns(server, args...)
+		assert.Equal(t, exitUsage, code, "%v", args)
+		assert.Empty(t, stdout, "%v", args)
+		assert.NotEmpty(t, stderr, "%v", args)
+	}
+	assert.Empty(t, server.Requests())
+}
+
+func TestZoneCommands(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+
+	code, _, stderr := udns(server, "zone", "create", "-account", "my-account", "example.com")
+	assert.Equal(t, exitOK, code, stderr)
+	zone, ok := server.Zone("example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "my-account", zone.Properties.AccountName)
+
+	code, _, stderr = udns(server, "zone", "create", "example.com")
+	assert.Equal(t, exitAlreadyExists, code)
+	assert.Contains(t, stderr, "udns: ")
+
+	server.AddZone("example.org")
+	code, stdout, _ := udns(server, "zone", "list")
+	assert.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "NAME "))
+	assert.True(t, strings.HasPrefix(lines[1], "example.com. "))
+
+	code, stdout, _ = udns(server, "-o", "json", "zone", "get", "example.org")
+	assert.Equal(t, exitOK, code)
+	got := ultradns.Zone{}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &got))
+	assert.Equal(t, "example.org.", got.Properties.Name)
+
+	code, _, _ = udns(server, "zone", "delete", "example.org")
+	assert.Equal(t, exitOK, code)
+	code, _, _ = udns(server, "zone", "get", "example.org")
+	assert.Equal(t, exitNotFound, code)
+}
+
+func TestRecordCommands(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.AddZone("example.com")
+
+	code, _, stderr := udns(server, "record", "add", "-ttl", "300", "example.com", "www", "A", "192.0.2.1")
+	assert.Equal(t, exitOK, code, stderr)
+	code, _, stderr = udns(server, "record", "add", "example.com", "www", "A", "192.0.2.2", "192.0.2.1")
+	assert.Equal(t, exitOK, code, stderr)
+	rrset, _ := server.RRSet("example.com", ultradns.RRTypeA, "www")
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, rrset.RData)
+	assert.Equal(t, 300, rrset.TTL)
+
+	code, stdout, _ := udns(server, "record", "list", "-type", "A", "example.com")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "OWNER             TYPE  TTL  RDATA\n"+
+		"www.example.com.  A     300  192.0.2.1\n"+
+		"www.example.com.  A     300  192.0.2.2\n", stdout)
+
+	code, _, stderr = udns(server, "record", "rm", "example.com", "www", "A", "192.0.2.1")
+	assert.Equal(t, exitOK, code, stderr)
+	rrset, _ = server.RRSet("example.com", ultradns.RRTypeA, "www")
+	assert.Equal(t, []string{"192.0.2.2"}, rrset.RData)
+
+	code, _, _ = udns(server, "record", "rm", "example.com", "www", "A", "192.0.2.9")
+	assert.Equal(t, exitNotFound, code)
+
+	code, _, stderr = udns(server, "record", "set", "example.com", "www", "A", "198.51.100.1")
+	assert.Equal(t, exitOK, code, stderr)
+	rrset, _ = server.RRSet("example.com", ultradns.RRTypeA, "www")
+	assert.Equal(t, []string{"198.51.100.1"}, rrset.RData)
+	assert.Equal(t, 300, rrset.TTL)
+
+	code, _, stderr = udns(server, "record", "rm", "example.com", "www", "A", "198.51.100.1")
+	assert.Equal(t, exitOK, code, stderr)
+	_, ok := server.RRSet("example.com", ultradns.RRTypeA, "www")
+	assert.False(t, ok)
+
+	code, _, _ = udns(server, "record", "rm", "example.com", "www", "A")
+	assert.Equal(t, exitNotFound, code)
+}
+
+func TestRecordListYAML(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.AddZone("example.com")
+	assert.NoError(t, server.PutRRSet("example.com", ultradns.RRSet{
+		OwnerName: "mail", RRType: ultradns.RRTypeMX, TTL: 3600, RData: []string{"10 mx.example.com."},
+	}))
+
+	code, stdout, stderr := udns(server, "-o", "yaml", "record", "list", "-owner", "mail", "example.com")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "- ownerName: mail.example.com.\n"+
+		"  rrtype: MX\n"+
+		"  ttl: 3600\n"+
+		"  rdata:\n"+
+		"  - 10 mx.example.com.\n", stdout)
+
+	code, stdout, _ = udns(server, "-o", "yaml", "record", "list", "-owner", "nothing", "example.com")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "[]\n", stdout)
+}
+
+func TestPoolCommands(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.AddZone("example.com")
+	assert.NoError(t, server.PutTCPool("example.com", ultradns.TCPool{
+		OwnerName: "pool",
+		RRType:    ultradns.RRTypeA,
+		RData:     []string{"192.0.2.1"},
+		Profile: ultradns.TCPoolProfile{
+			Description: "web",
+			RDataInfo:   []ultradns.TCPoolRDataInfo{{State: ultradns.TCPoolStateNormal, Priority: 1, Threshold: 1}},
+		},
+	}))
+
+	code, stdout, stderr := udns(server, "pool", "list", "example.com")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "pool.example.com.")
+	assert.Contains(t, stdout, "web")
+
+	code, _, stderr = udns(server, "pool", "add", "-priority", "2", "example.com", "pool", "192.0.2.2")
+	assert.Equal(t, exitOK, code, stderr)
+	code, _, stderr = udns(server, "pool", "state", "example.com", "pool", "192.0.2.1", ultradns.TCPoolStateInactive)
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ = udns(server, "pool", "get", "example.com", "pool")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "RDATA      STATE     PRIORITY  THRESHOLD  AVAILABLE\n"+
+		"192.0.2.1  INACTIVE  1         1          false\n"+
+		"192.0.2.2  NORMAL    2         1          false\n", stdout)
+
+	code, _, stderr = udns(server, "pool", "rm", "example.com", "pool", "192.0.2.1")
+	assert.Equal(t, exitOK, code, stderr)
+	pool, _ := server.RRSet("example.com", ultradns.RRTypeA, "pool")
+	assert.Equal(t, []string{"192.0.2.2"}, pool.RData)
+}
+
+func TestTaskWait(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.TaskPolls = 2
+	id := server.AddTask(ultradns.Task{Code: ultradns.TaskPending})
+	failed := server.AddTask(ultradns.Task{Code: ultradns.TaskFailed, Message: "zone is locked"})
+
+	code, stdout, stderr := udns(server, "-o", "json", "task", "wait", "-interval", "1ms", id)
+	assert.Equal(t, exitOK, code, stderr)
+	task := ultradns.Task{}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &task))
+	assert.Equal(t, ultradns.TaskComplete, task.Code)
+
+	code, stdout, stderr = udns(server, "task", "wait", failed)
+	assert.Equal(t, exitTaskFailed, code)
+	assert.Contains(t, stdout, "zone is locked")
+	assert.Contains(t, stderr, "zone is locked")
+}
+
+func TestNoWaitPrintsTaskID(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.Async = true
+	server.TaskPolls = 1
+
+	code, stdout, stderr := udns(server, "-wait=false", "zone", "create", "-account", "my-account", "example.com")
+	assert.Equal(t, exitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "TASK", lines[0])
+		code, stdout, stderr = udns(server, "-o", "json", "task", "wait", "-interval", "1ms", lines[1])
+		assert.Equal(t, exitOK, code, stderr)
+		assert.Contains(t, stdout, ultradns.TaskComplete)
+	}
+
+	// Waiting for the change, nothing is printed.
+	code, stdout, stderr = udns(server, "record", "add", "example.com", "www", "A", "192.0.2.1")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, stdout)
+}
+
+func TestExitCodes(t *testing.T) {
+	server, done := newTestServer()
+	defer done()
+	server.AddZone("example.com")
+
+	server.FailNext("/status", http.StatusServiceUnavailable, 0)
+	server.FailNext("/status", http.StatusServiceUnavailable, 0)
+	server.FailNext("/status", http.StatusServiceUnavailable, 0)
+	code, _, _ := udns(server, "status")
+	assert.Equal(t, exitServerError, code)
+
+	code, _, _ = udns(server, "record", "add", "example.com", "www", "A", "not-an-ip")
+	assert.Equal(t, exitValidation, code)
+	server.FailNext("/zones/*/rrsets/*/*", http.StatusBadRequest, ultradnstest.CodeInvalidInput)
+	code, _, _ = udns(server, "record", "add", "example.com", "www", "A", "192.0.2.1")
+	assert.Equal(t, exitValidation, code)
+
+	restore := setCredentials(ultradnstest.Username, "wrong")
+	code, _, _ = udns(server, "status")
+	restore()
+	assert.Equal(t, exitUnauthorized, code)
+}
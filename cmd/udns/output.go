@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// table is the tabular form of a command's result.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// print writes the result in the selected format: v encoded as JSON or YAML, or the table.
+func (c *cli) print(v interface{}, t *table) error {
+	switch c.format {
+	case "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", data)
+		return err
+	case "yaml":
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = c.stdout.Write(data)
+		return err
+	default:
+		return t.write(c)
+	}
+}
+
+// write aligns the columns of the table.
+func (t *table) write(c *cli) error {
+	w := tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// toYAML encodes v as YAML using its JSON field names. Going through JSON also applies the custom encodings, e.g. of
+// record types, and decoding into yaml.MapSlice keeps the members in their JSON order. v must be an object or a list
+// of objects.
+func toYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var ordered interface{}
+	if len(data) > 0 && data[0] == '[' {
+		list := []yaml.MapSlice{}
+		err = yaml.Unmarshal(data, &list)
+		ordered = list
+	} else {
+		object := yaml.MapSlice{}
+		err = yaml.Unmarshal(data, &object)
+		ordered = object
+	}
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(ordered)
+}
+
+// names returns the sorted subcommand names.
+func names(subcommands map[string]command) []string {
+	var result []string
+	for name := range subcommands {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// itoa formats a number for a table cell, leaving zero values empty.
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// poolCommand is the parsed command line of a command addressing a single pool.
+type poolCommand struct {
+	flags    *flag.FlagSet
+	typeName *string
+	zone     string
+	owner    string
+	rrtype   ultradns.RRType
+}
+
+// newPoolCommand returns the flag set of a pool command, with the -type flag. Further flags may be added before parse.
+func newPoolCommand(name string) *poolCommand {
+	flags := newFlagSet(name)
+	return &poolCommand{flags: flags, typeName: flags.String("type", "A", "")}
+}
+
+// parse parses the command line, which starts with the zone and owner of the pool followed by n more arguments.
+func (p *poolCommand) parse(args []string, n int) error {
+	if err := parse(p.flags, args, 2+n, 2+n); err != nil {
+		return err
+	}
+	rrtype, err := ultradns.ParseRRType(*p.typeName)
+	if err != nil {
+		return usagef("%s", err)
+	}
+	p.zone, p.owner, p.rrtype = p.flags.Arg(0), p.flags.Arg(1), rrtype
+	return nil
+}
+
+// poolTable returns the table of the pools.
+func poolTable(pools []ultradns.TCPool) *table {
+	t := &table{header: []string{"OWNER", "TYPE", "TTL", "MEMBERS", "STATUS", "DESCRIPTION"}}
+	for _, pool := range pools {
+		t.rows = append(t.rows, []string{
+			pool.OwnerName, pool.RRType.String(), itoa(pool.TTL), strconv.Itoa(len(pool.RData)), pool.Profile.Status,
+			pool.Profile.Description,
+		})
+	}
+	return t
+}
+
+// memberTable returns the table of the members of the pool.
+func memberTable(pool *ultradns.TCPool) *table {
+	t := &table{header: []string{"RDATA", "STATE", "PRIORITY", "THRESHOLD", "AVAILABLE"}}
+	for i, rdata := range pool.RData {
+		var info ultradns.TCPoolRDataInfo
+		if i < len(pool.Profile.RDataInfo) {
+			info = pool.Profile.RDataInfo[i]
+		}
+		t.rows = append(t.rows, []string{
+			rdata, info.State, strconv.Itoa(info.Priority), strconv.Itoa(info.Threshold),
+			strconv.FormatBool(info.AvailableToServe),
+		})
+	}
+	return t
+}
+
+// poolList lists the Traffic Controller pools of a zone.
+func poolList(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("pool list")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	pools, err := c.apiConn.TCPools().List(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if pools == nil {
+		pools = []ultradns.TCPool{}
+	}
+	return c.print(pools, poolTable(pools))
+}
+
+// poolGet shows a pool and its members.
+func poolGet(ctx context.Context, c *cli, args []string) error {
+	p := newPoolCommand("pool get")
+	if err := p.parse(args, 0); err != nil {
+		return err
+	}
+	pool, err := c.apiConn.TCPools().Get(ctx, p.zone, p.rrtype, p.owner)
+	if err != nil {
+		return err
+	}
+	return c.print(pool, memberTable(pool))
+}
+
+// poolAdd adds a member to a pool.
+func poolAdd(ctx context.Context, c *cli, args []string) error {
+	p := newPoolCommand("pool add")
+	info := ultradns.TCPoolRDataInfo{RunProbes: true}
+	p.flags.IntVar(&info.Priority, "priority", 1, "")
+	p.flags.IntVar(&info.Threshold, "threshold", 1, "")
+	p.flags.StringVar(&info.State, "state", ultradns.TCPoolStateNormal, "")
+	if err := p.parse(args, 1); err != nil {
+		return err
+	}
+	return c.changed(c.apiConn.TCPools().AddPoolMember(ctx, p.zone, p.rrtype, p.owner, p.flags.Arg(2), info))
+}
+
+// poolRemove removes a member from a pool.
+func poolRemove(ctx context.Context, c *cli, args []string) error {
+	p := newPoolCommand("pool rm")
+	if err := p.parse(args, 1); err != nil {
+		return err
+	}
+	return c.changed(c.apiConn.TCPools().RemovePoolMember(ctx, p.zone, p.rrtype, p.owner, p.flags.Arg(2)))
+}
+
+// poolState sets the state of a pool member.
+func poolState(ctx context.Context, c *cli, args []string) error {
+	p := newPoolCommand("pool state")
+	if err := p.parse(args, 2); err != nil {
+		return err
+	}
+	return c.changed(c.apiConn.TCPools().SetMemberState(ctx, p.zone, p.rrtype, p.owner, p.flags.Arg(2), p.flags.Arg(3)))
+}
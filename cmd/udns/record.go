@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// rrsetTable returns the table of the record sets, with a row per record.
+func rrsetTable(rrsets []ultradns.RRSet) *table {
+	t := &table{header: []string{"OWNER", "TYPE", "TTL", "RDATA"}}
+	for _, rrset := range rrsets {
+		for _, rdata := range rrset.RData {
+			t.rows = append(t.rows, []string{rrset.OwnerName, rrset.RRType.String(), itoa(rrset.TTL), rdata})
+		}
+	}
+	return t
+}
+
+// rrsetArgs parses the zone, owner and type arguments shared by the record commands.
+func rrsetArgs(args []string) (zone string, owner string, rrtype ultradns.RRType, err error) {
+	rrtype, err = ultradns.ParseRRType(args[2])
+	if err != nil {
+		return "", "", 0, usagef("%s", err)
+	}
+	return args[0], args[1], rrtype, nil
+}
+
+// validateRData checks that the records parse for the type before anything is sent.
+func validateRData(rrtype ultradns.RRType, rdata []string) error {
+	if err := (&ultradns.RRSet{RRType: rrtype, RData: rdata}).Validate(); err != nil {
+		return fmt.Errorf("%s: %w", err, apierror.Validation)
+	}
+	return nil
+}
+
+// getRRSet fetches the record set, returning nil if it does not exist.
+func getRRSet(ctx context.Context, c *cli, zone string, owner string, rrtype ultradns.RRType) (*ultradns.RRSet, error) {
+	rrset, err := c.apiConn.RRSets().Get(ctx, zone, rrtype, owner)
+	if errors.Is(err, apierror.NotFound) {
+		return nil, nil
+	}
+	return rrset, err
+}
+
+// recordList lists the record sets of a zone, optionally only those of a type or at an owner.
+func recordList(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("record list")
+	typeName := flags.String("type", "", "")
+	owner := flags.String("owner", "", "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	var rrtype ultradns.RRType
+	if *typeName != "" {
+		var err error
+		if rrtype, err = ultradns.ParseRRType(*typeName); err != nil {
+			return usagef("%s", err)
+		}
+	}
+	opts := &ultradns.ListOptions{}
+	if *owner != "" {
+		opts.Q = "owner:" + *owner
+	}
+	all, err := c.apiConn.RRSets().ListAll(ctx, flags.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	rrsets := []ultradns.RRSet{}
+	for _, rrset := range all {
+		if rrtype == 0 || rrset.RRType == rrtype {
+			rrsets = append(rrsets, rrset)
+		}
+	}
+	return c.print(rrsets, rrsetTable(rrsets))
+}
+
+// recordAdd adds records to a record set, creating it if it does not exist. Records it already has are skipped.
+func recordAdd(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("record add")
+	ttl := flags.Int("ttl", 0, "")
+	if err := parse(flags, args, 4, -1); err != nil {
+		return err
+	}
+	zone, owner, rrtype, err := rrsetArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+	rdata := flags.Args()[3:]
+	if err = validateRData(rrtype, rdata); err != nil {
+		return err
+	}
+
+	current, err := getRRSet(ctx, c, zone, owner, rrtype)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return c.changed(c.apiConn.RRSets().Create(ctx, zone, &ultradns.RRSet{OwnerName: owner, RRType: rrtype, TTL: *ttl, RData: rdata}))
+	}
+	desired := *current
+	desired.RData = append([]string(nil), current.RData...)
+	for _, record := range rdata {
+		if indexOf(desired.RData, record) < 0 {
+			desired.RData = append(desired.RData, record)
+		}
+	}
+	if *ttl != 0 {
+		desired.TTL = *ttl
+	}
+	return c.changed(c.apiConn.RRSets().Update(ctx, zone, current, &desired))
+}
+
+// recordRemove removes records from a record set, deleting it once it is empty. Without records, the whole record
+// set is deleted.
+func recordRemove(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("record rm")
+	if err := parse(flags, args, 3, -1); err != nil {
+		return err
+	}
+	zone, owner, rrtype, err := rrsetArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+	rdata := flags.Args()[3:]
+	if len(rdata) == 0 {
+		return c.changed(c.apiConn.RRSets().Delete(ctx, zone, rrtype, owner))
+	}
+
+	current, err := c.apiConn.RRSets().Get(ctx, zone, rrtype, owner)
+	if err != nil {
+		return err
+	}
+	desired := *current
+	desired.RData = append([]string(nil), current.RData...)
+	for _, record := range rdata {
+		i := indexOf(desired.RData, record)
+		if i < 0 {
+			return fmt.Errorf("%s record set at %s has no record '%s': %w", rrtype, current.OwnerName, record, apierror.NotFound)
+		}
+		desired.RData = append(desired.RData[:i], desired.RData[i+1:]...)
+	}
+	if len(desired.RData) == 0 {
+		return c.changed(c.apiConn.RRSets().Delete(ctx, zone, rrtype, owner))
+	}
+	return c.changed(c.apiConn.RRSets().Update(ctx, zone, current, &desired))
+}
+
+// recordSet replaces the records of a record set, creating it if it does not exist. The TTL is kept unless -ttl is
+// given.
+func recordSet(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("record set")
+	ttl := flags.Int("ttl", 0, "")
+	if err := parse(flags, args, 4, -1); err != nil {
+		return err
+	}
+	zone, owner, rrtype, err := rrsetArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+	rrset := &ultradns.RRSet{OwnerName: owner, RRType: rrtype, TTL: *ttl, RData: flags.Args()[3:]}
+	if err = validateRData(rrtype, rrset.RData); err != nil {
+		return err
+	}
+
+	current, err := getRRSet(ctx, c, zone, owner, rrtype)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return c.changed(c.apiConn.RRSets().Create(ctx, zone, rrset))
+	}
+	if rrset.TTL == 0 {
+		rrset.TTL = current.TTL
+	}
+	return c.changed(c.apiConn.RRSets().Replace(ctx, zone, rrset))
+}
+
+// indexOf returns the position of s in list, or -1.
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// taskTable returns the table of the task.
+func taskTable(task *ultradns.Task) *table {
+	return &table{
+		header: []string{"ID", "STATUS", "MESSAGE"},
+		rows:   [][]string{{task.TaskID, string(task.Code), task.Message}},
+	}
+}
+
+// taskGet shows a task.
+func taskGet(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("task get")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	task, err := c.apiConn.Tasks().Get(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.print(task, taskTable(task))
+}
+
+// taskWait waits for a task to finish and shows it. A failed task is shown as well before its error is returned.
+func taskWait(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("task wait")
+	interval := flags.Duration("interval", 0, "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	tasks := c.apiConn.Tasks()
+	// A given interval is used for every poll, rather than backing off from it.
+	tasks.PollInterval, tasks.MaxPollInterval = *interval, *interval
+	task, err := tasks.Wait(ctx, flags.Arg(0))
+	var taskErr *ultradns.TaskError
+	if err != nil && !errors.As(err, &taskErr) {
+		return err
+	}
+	if printErr := c.print(task, taskTable(task)); printErr != nil {
+		return printErr
+	}
+	return err
+}
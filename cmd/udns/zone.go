@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// zoneTable returns the table of the zones.
+func zoneTable(zones []ultradns.Zone) *table {
+	t := &table{header: []string{"NAME", "TYPE", "STATUS", "RECORDS", "ACCOUNT"}}
+	for _, zone := range zones {
+		p := zone.Properties
+		t.rows = append(t.rows, []string{p.Name, p.Type, p.Status, itoa(p.ResourceRecordCount), p.AccountName})
+	}
+	return t
+}
+
+// zoneList lists the zones, optionally filtered by an UltraDNS query.
+func zoneList(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("zone list")
+	query := flags.String("q", "", "")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	zones, err := c.apiConn.Zones().ListAll(ctx, &ultradns.ListOptions{Q: *query})
+	if err != nil {
+		return err
+	}
+	if zones == nil {
+		zones = []ultradns.Zone{}
+	}
+	return c.print(zones, zoneTable(zones))
+}
+
+// zoneGet shows a zone.
+func zoneGet(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("zone get")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	zone, err := c.apiConn.Zones().Get(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.print(zone, zoneTable([]ultradns.Zone{*zone}))
+}
+
+// zoneCreate creates an empty primary zone.
+func zoneCreate(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("zone create")
+	account := flags.String("account", os.Getenv("ULTRADNS_ACCOUNT"), "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	return c.changed(c.apiConn.Zones().CreatePrimary(ctx, flags.Arg(0), *account))
+}
+
+// zoneDelete deletes a zone.
+func zoneDelete(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("zone delete")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	return c.changed(c.apiConn.Zones().Delete(ctx, flags.Arg(0)))
+}
@@ -4,46 +4,31 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/simplifi/ultradns-go/pkg/ultradns"
 )
 
 func main() {
-	// Use a couple of flags to allow passing in the username/password.
-	userPtr := flag.String("user", "", "Username for UltraDNS API")
-	passPtr := flag.String("pass", "", "Password for UltraDNS API")
+	profilePtr := flag.String("profile", "", "Credentials profile")
 
 	flag.Parse()
 
-	if *userPtr == "" || *passPtr == "" {
-		flag.PrintDefaults()
-		return
-	}
-
-	// Create an APIConnection with the username/password provided.
+	// Create an APIConnection that reads the credentials from the environment or the profile (see the README).
 	apiConn := ultradns.NewAPIConnection(&ultradns.APIOptions{
-		Username: *userPtr,
-		Password: *passPtr,
+		Credentials: ultradns.DefaultCredentials(*profilePtr),
 	})
 
-	// Make a GET request to the /status endpoint
-	resp, err := apiConn.Get("/status")
-	if err != nil {
-		fmt.Print("Error in apiConn.Get: ")
+	// Make a GET request to the /status endpoint and decode the response. GetJSON closes the body, also on errors.
+	status := struct {
+		Message string `json:"message"`
+	}{}
+	if err := apiConn.GetJSON("/status", &status); err != nil {
+		fmt.Print("Error in apiConn.GetJSON: ")
 		fmt.Println(err)
 		return
 	}
-	// The body of the response is an http.Response.Body ReadCloser. It needs to be closed after being read.
-	defer resp.Body.Close()
-	// Read the body into a byte array.
-	var bodyBytes []byte
-	if bodyBytes, err = ioutil.ReadAll(resp.Body); err != nil {
-		fmt.Printf("Error in ioutil.ReadAll: %s :\n", err)
-		return
-	}
 
-	// Print out the body of the response (should be `{"message":"Good"}`)
+	// Print out the status (should be `Good`)
 	fmt.Println("Success:")
-	fmt.Println(string(bodyBytes))
+	fmt.Println(status.Message)
 }
@@ -4,96 +4,77 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/simplifi/ultradns-go/pkg/ultradns"
 )
 
 func main() {
-	userPtr := flag.String("user", "", "Username for UltraDNS API")
-	passPtr := flag.String("pass", "", "Password for UltraDNS API")
+	profilePtr := flag.String("profile", "", "Credentials profile")
 	zonePtr := flag.String("zone", "", "Zone to look at, e.g. your main domain, like 'example.com'")
 	trafficControllerPtr := flag.String("tc-name", "", "Address of Traffic Controller to query, e.g. 'my.example.com'")
 	addIPPtr := flag.String("add-ip", "", "IP Address to add to the trafficcontroller")
+	disableIPPtr := flag.String("disable-ip", "", "IP Address to set INACTIVE in the trafficcontroller")
 
 	flag.Parse()
 
-	if *userPtr == "" || *passPtr == "" || *zonePtr == "" {
+	if *zonePtr == "" {
 		flag.PrintDefaults()
 		return
 	}
 
-	// Return all the pools if a specific one wasn't requested.
-	url := "/zones/" + *zonePtr + "/rrsets"
-	if *trafficControllerPtr == "" {
-		fmt.Println("No -tc-name option passed, listing all TrafficController pools.")
-		url = url + "?q=kind:TC_POOLS"
-	} else {
-		// This just assumes an 'A' record for simplicity.
-		url = "/A/" + *trafficControllerPtr
-	}
-
-	// Create an APIConnection with the username/password provided.
+	// Create an APIConnection that resolves the credentials when it first needs a token.
 	apiConn := ultradns.NewAPIConnection(&ultradns.APIOptions{
-		Username: *userPtr,
-		Password: *passPtr,
+		Credentials: ultradns.DefaultCredentials(*profilePtr),
 	})
 
-	// Some ugliness here, but it's just an example of API usage.
-	switch {
-	case *addIPPtr != "":
-		patchArr := make([]Patch, 2)
-		patchArr[0] = Patch{
-			Op:    "add",
-			Path:  "/rdata/0",
-			Value: *addIPPtr,
-		}
-		patchArr[1] = Patch{
-			Op:   "add",
-			Path: "/profile/rdataInfo/0",
-			Value: map[string]interface{}{
-				"state":    "NORMAL",
-				"priority": 1,
-			},
-		}
+	pools := apiConn.TCPools()
+	ctx := context.Background()
 
-		body, err := json.Marshal(patchArr)
+	// Return all the pools if a specific one wasn't requested.
+	if *trafficControllerPtr == "" {
+		fmt.Println("No -tc-name option passed, listing all TrafficController pools.")
+		all, err := pools.List(ctx, *zonePtr)
 		if err != nil {
 			panic(err)
 		}
+		for _, pool := range all {
+			fmt.Printf("%s %s %v\n", pool.OwnerName, pool.RRType, pool.RData)
+		}
+		return
+	}
 
-		fmt.Printf("Sending JSON PATCH request to %s with body %s\n", url, body)
-		resp, err := apiConn.JSONPatch(url, bytes.NewReader(body))
-		if err != nil {
+	// This just assumes an 'A' record for simplicity.
+	if *addIPPtr != "" {
+		info := ultradns.TCPoolRDataInfo{
+			State:     ultradns.TCPoolStateNormal,
+			RunProbes: true,
+			Priority:  1,
+			Threshold: 1,
+		}
+		fmt.Printf("Adding %s to %s\n", *addIPPtr, *trafficControllerPtr)
+		if _, err := pools.AddPoolMember(ctx, *zonePtr, ultradns.RRTypeA, *trafficControllerPtr, *addIPPtr, info); err != nil {
 			panic(err)
 		}
-		bodyBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
+	}
+
+	if *disableIPPtr != "" {
+		fmt.Printf("Disabling %s in %s\n", *disableIPPtr, *trafficControllerPtr)
+		if _, err := pools.SetMemberState(ctx, *zonePtr, ultradns.RRTypeA, *trafficControllerPtr, *disableIPPtr, ultradns.TCPoolStateInactive); err != nil {
 			panic(err)
 		}
-		fmt.Printf("Update returned %s\n", string(bodyBytes))
 	}
 
-	resp, err := apiConn.Get(url)
+	pool, err := pools.Get(ctx, *zonePtr, ultradns.RRTypeA, *trafficControllerPtr)
 	if err != nil {
 		panic(err)
 	}
-	defer resp.Body.Close()
-	var bodyBytes []byte
-	if bodyBytes, err = ioutil.ReadAll(resp.Body); err != nil {
-		fmt.Printf("Error in ioutil.ReadAll: %s\n", err)
-	}
 
-	fmt.Printf("New TrafficController configuration: %s", string(bodyBytes))
-}
-
-// Patch is the object structure required for the TrafficController update JSON Patch API.
-type Patch struct {
-	Op    string      `json:"op"`
-	Path  string      `json:"path"`
-	Value interface{} `json:"value"`
+	fmt.Println("TrafficController configuration:")
+	for i, rdata := range pool.RData {
+		info := pool.Profile.RDataInfo[i]
+		fmt.Printf("%s state=%s priority=%d\n", rdata, info.State, info.Priority)
+	}
 }
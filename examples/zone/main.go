@@ -2,54 +2,49 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/simplifi/ultradns-go/pkg/ultradns"
 )
 
 func main() {
-	// Use a couple of flags to allow passing in the username/password.
-	userPtr := flag.String("user", "", "Username for UltraDNS API")
-	passPtr := flag.String("pass", "", "Password for UltraDNS API")
+	profilePtr := flag.String("profile", "", "Credentials profile")
 	zonePtr := flag.String("zone", "", "Zone to view")
 
 	flag.Parse()
 
-	if *userPtr == "" || *passPtr == "" {
-		flag.PrintDefaults()
-		return
-	}
-
-	// Create an APIConnection with the username/password provided.
+	// Create an APIConnection that resolves the credentials when it first needs a token.
 	apiConn := ultradns.NewAPIConnection(&ultradns.APIOptions{
-		Username: *userPtr,
-		Password: *passPtr,
+		Credentials: ultradns.DefaultCredentials(*profilePtr),
 	})
 
-	url := "/zones/"
-	if *zonePtr != "" {
-		url = url + *zonePtr
+	zones := apiConn.Zones()
+	ctx := context.Background()
+
+	// Without a zone, list the first page of zones in the account.
+	if *zonePtr == "" {
+		list, err := zones.List(ctx, nil)
+		if err != nil {
+			fmt.Print("Error in zones.List: ")
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Showing %d of %d zones:\n", list.ResultInfo.ReturnedCount, list.ResultInfo.TotalCount)
+		for _, zone := range list.Zones {
+			fmt.Printf("%s (%s, %d records)\n", zone.Properties.Name, zone.Properties.Type, zone.Properties.ResourceRecordCount)
+		}
+		return
 	}
 
-	// Make a GET request to the /status endpoint
-	resp, err := apiConn.Get(url)
+	zone, err := zones.Get(ctx, *zonePtr)
 	if err != nil {
-		fmt.Print("Error in apiConn.Get: ")
+		fmt.Print("Error in zones.Get: ")
 		fmt.Println(err)
 		return
 	}
-	// The body of the response is an http.Response.Body ReadCloser. It needs to be closed after being read.
-	defer resp.Body.Close()
-	// Read the body into a byte array.
-	var bodyBytes []byte
-	if bodyBytes, err = ioutil.ReadAll(resp.Body); err != nil {
-		fmt.Printf("Error in ioutil.ReadAll: %s :\n", err)
-		return
-	}
 
-	// Print out the body of the response (should be `{"message":"Good"}`)
 	fmt.Println("Success:")
-	fmt.Println(string(bodyBytes))
+	fmt.Printf("%+v\n", zone.Properties)
 }
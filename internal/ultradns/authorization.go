@@ -1,13 +1,16 @@
 package ultradns
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,6 +24,30 @@ type Authorization struct {
 	TokenExpires int64
 	RefreshToken string
 	BaseURL      string
+
+	// Credentials, when set, supplies the Username/Password and an initial RefreshToken whenever a token has to be
+	// requested and the Username/Password fields are empty.
+	Credentials CredentialsProvider
+
+	// seeded is set once the RefreshToken from Credentials was used, so that it isn't tried again after it was
+	// replaced or rejected.
+	seeded bool
+
+	// Store, when set, is consulted before requesting tokens and receives every new token, so that tokens are shared
+	// with other Authorizations using the same store.
+	Store TokenStore
+
+	// rejected is the last token passed to Invalidate, which must not be loaded from the Store again.
+	rejected string
+
+	// inflight is the token request currently being made, if any. Guarded by the mutex.
+	inflight *authCall
+}
+
+// authCall is a token request whose outcome is shared by every goroutine that needed a token while it was made.
+type authCall struct {
+	done chan struct{}
+	err  error
 }
 
 // NewAuthorization returns an initialized Authorization struct
@@ -39,24 +66,222 @@ func NewAuthorization(username string, password string) *Authorization {
 // Threadsafe.
 // Checks the expiration of the current AccessToken. Does nothing if the AccessToken is not close to expiration.
 // If the token has expired, then it will ask for a new token using the RefreshToken.
-// If the RefreshToken is not available, then authorizes using the username/password.
-// In any cases where authorization is performed, the struct is locked and updated with the new Tokens.
+// If the RefreshToken is not available, or it is rejected, then authorizes using the username/password.
+// If both are rejected, an *AuthorizationError holding both errors is returned.
+// Concurrent calls that find the token expired share a single token request instead of each making their own.
 func (auth *Authorization) Authorize(client *http.Client) error {
-	if auth.tokenIsValid() {
-		return nil
+	return auth.AuthorizeContext(context.Background(), client)
+}
+
+// AuthorizeContext is like Authorize, but the token request is bound to the given context. Cancelling the context or
+// exceeding its deadline aborts an in-flight token request.
+func (auth *Authorization) AuthorizeContext(ctx context.Context, client *http.Client) error {
+	_, err := auth.TokenContext(ctx, client)
+	return err
+}
+
+// Token authorizes if necessary, like Authorize, and returns the AccessToken to send with API requests.
+// Threadsafe.
+func (auth *Authorization) Token(client *http.Client) (string, error) {
+	return auth.TokenContext(context.Background(), client)
+}
+
+// TokenContext is like Token, but the token request is bound to the given context.
+//
+// Only one token request is made at a time. Callers arriving while it is in flight wait for its outcome instead of
+// making their own; a waiter whose context is done stops waiting without affecting the request. If the request fails
+// only because the context of the caller that made it was done, the remaining waiters try again themselves.
+func (auth *Authorization) TokenContext(ctx context.Context, client *http.Client) (string, error) {
+	for {
+		auth.Lock()
+		if auth.tokenIsValid() {
+			token := auth.AccessToken
+			auth.Unlock()
+			return token, nil
+		}
+		call := auth.inflight
+		leader := call == nil
+		if leader {
+			call = &authCall{done: make(chan struct{})}
+			auth.inflight = call
+		}
+		auth.Unlock()
+
+		if leader {
+			call.err = auth.authorize(ctx, client)
+			auth.Lock()
+			auth.inflight = nil
+			token := auth.AccessToken
+			auth.Unlock()
+			close(call.done)
+			if call.err != nil {
+				return "", call.err
+			}
+			return token, nil
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if call.err == nil {
+			auth.Lock()
+			token := auth.AccessToken
+			auth.Unlock()
+			return token, nil
+		}
+		if !isContextError(call.err) || ctx.Err() != nil {
+			return "", call.err
+		}
+		// The request was abandoned by the caller that made it, try again.
+	}
+}
+
+// Invalidate discards the AccessToken if it is still the given one, so that the next call to Authorize requests a
+// new token. Use it when the API rejected a token before TokenExpires, e.g. because it was revoked. Passing the
+// rejected token, rather than discarding whatever token is current, keeps a token that was already replaced by a
+// concurrent re-authorization.
+// Threadsafe.
+func (auth *Authorization) Invalidate(token string) {
+	auth.Lock()
+	defer auth.Unlock()
+	if auth.AccessToken == token {
+		auth.AccessToken = ""
+		auth.TokenExpires = 0
+	}
+	auth.rejected = token
+}
+
+// authorize obtains new tokens. With a Store, the store is locked while doing so, and valid tokens saved to it by
+// someone else are used instead of requesting new ones.
+// Only called by the goroutine leading the in-flight token request.
+func (auth *Authorization) authorize(ctx context.Context, client *http.Client) error {
+	auth.Lock()
+	store := auth.Store
+	auth.Unlock()
+	if store == nil {
+		return auth.requestTokens(ctx, client)
+	}
+
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		auth.Lock()
+		usable := stored.valid() && stored.AccessToken != auth.rejected
+		if usable {
+			auth.AccessToken = stored.AccessToken
+			auth.TokenExpires = stored.TokenExpires
+		}
+		// Refresh tokens can only be used once, so the stored one is the newest even if the access token isn't usable.
+		if stored.RefreshToken != "" {
+			auth.RefreshToken = stored.RefreshToken
+		}
+		auth.Unlock()
+		if usable {
+			return nil
+		}
+	}
+
+	if err = auth.requestTokens(ctx, client); err != nil {
+		return err
+	}
+	auth.Lock()
+	tokens := &Tokens{AccessToken: auth.AccessToken, RefreshToken: auth.RefreshToken, TokenExpires: auth.TokenExpires}
+	auth.Unlock()
+	if err = store.Save(tokens); err != nil {
+		return fmt.Errorf("saving tokens: %w", err)
+	}
+	return nil
+}
+
+// requestTokens requests new tokens, using the RefreshToken if there is one and falling back to the
+// username/password.
+func (auth *Authorization) requestTokens(ctx context.Context, client *http.Client) error {
+	username, password, refreshToken, err := auth.credentials(ctx)
+	if err != nil {
+		return err
 	}
 
+	if refreshToken == "" {
+		return auth.requestToken(ctx, client, passwordQuery(username, password))
+	}
+
+	refreshErr := auth.requestToken(ctx, client, refreshQuery(refreshToken))
+	if refreshErr == nil || !isInvalidGrant(refreshErr) || username == "" || password == "" {
+		return refreshErr
+	}
+
+	// The refresh token was rejected, e.g. because it expired or was already used. Forget it, so that the next
+	// authorization doesn't try it again either.
+	auth.Lock()
+	auth.RefreshToken = ""
+	auth.Unlock()
+
+	if passwordErr := auth.requestToken(ctx, client, passwordQuery(username, password)); passwordErr != nil {
+		return &AuthorizationError{RefreshTokenErr: refreshErr, PasswordErr: passwordErr}
+	}
+	return nil
+}
+
+// credentials returns the credentials to request tokens with, resolving them from the Credentials provider if the
+// Username/Password are not set.
+func (auth *Authorization) credentials(ctx context.Context) (username string, password string, refreshToken string, err error) {
+	auth.Lock()
+	username, password, refreshToken = auth.Username, auth.Password, auth.RefreshToken
+	provider, seeded := auth.Credentials, auth.seeded
+	auth.Unlock()
+	if provider == nil || username != "" || password != "" {
+		return username, password, refreshToken, nil
+	}
+
+	credentials, err := provider.Credentials(ctx)
+	if err != nil {
+		// A RefreshToken obtained earlier can still be used without the provider.
+		if errors.Is(err, ErrNoCredentials) && refreshToken != "" {
+			return "", "", refreshToken, nil
+		}
+		return "", "", "", fmt.Errorf("resolving credentials: %w", err)
+	}
+	if refreshToken == "" && !seeded {
+		refreshToken = credentials.RefreshToken
+		auth.Lock()
+		auth.seeded = true
+		auth.Unlock()
+	}
+	return credentials.Username, credentials.Password, refreshToken, nil
+}
+
+// requestToken requests new tokens from the token endpoint with the given grant and stores them.
+func (auth *Authorization) requestToken(ctx context.Context, client *http.Client, query url.Values) error {
 	var bodyBytes []byte
 
-	resp, err := client.PostForm(auth.BaseURL+"/authorization/token", auth.authQuery())
+	auth.Lock()
+	baseURL := auth.BaseURL
+	auth.Unlock()
 
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/authorization/token", strings.NewReader(query.Encode()))
 	if err != nil {
 		return err
 	}
-	if err = GetError(resp); err != nil {
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if err = GetError(resp); err != nil {
+		return err
+	}
 
 	if bodyBytes, err = ioutil.ReadAll(resp.Body); err != nil {
 		return err
@@ -70,6 +295,20 @@ func (auth *Authorization) Authorize(client *http.Client) error {
 	return auth.updateTokens(&authJSON, clientTimeout)
 }
 
+// isInvalidGrant returns true if the token endpoint rejected the credentials, as opposed to e.g. being unreachable.
+func isInvalidGrant(err error) bool {
+	errorResponse := ErrorResponse{}
+	if !errors.As(err, &errorResponse) {
+		return false
+	}
+	return errorResponse.ErrorType() == "invalid_grant" || strings.HasPrefix(errorResponse.ErrorMessage(), "invalid_grant")
+}
+
+// isContextError returns true if err was caused by a cancelled context or an exceeded deadline.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // Update the tokens from the UltraDNS response. Locks the auth.
 func (auth *Authorization) updateTokens(response *tokenResponse, padding int64) error {
 	currentEpoch := time.Now().Unix()
@@ -92,23 +331,24 @@ func (auth *Authorization) updateTokens(response *tokenResponse, padding int64)
 	return nil
 }
 
-// authQuery returns the correct url.Values struct based on whether the RefreshToken is available
-func (auth *Authorization) authQuery() url.Values {
-	if auth.RefreshToken == "" {
-		return url.Values{
-			"grant_type": {"password"},
-			"username":   {auth.Username},
-			"password":   {auth.Password},
-		}
+// passwordQuery returns the url.Values for authorizing with the username/password
+func passwordQuery(username string, password string) url.Values {
+	return url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
 	}
+}
 
+// refreshQuery returns the url.Values for authorizing with the RefreshToken
+func refreshQuery(refreshToken string) url.Values {
 	return url.Values{
 		"grant_type":    {"refresh_token"},
-		"refresh_token": {auth.RefreshToken},
+		"refresh_token": {refreshToken},
 	}
 }
 
-// tokenIsValid Returns true if the Authorization struct has an unexpired AccessToken
+// tokenIsValid Returns true if the Authorization struct has an unexpired AccessToken. The caller must hold the lock.
 func (auth *Authorization) tokenIsValid() bool {
 	if auth.AccessToken == "" {
 		return false
@@ -116,8 +356,19 @@ func (auth *Authorization) tokenIsValid() bool {
 	return time.Now().Unix() < auth.TokenExpires
 }
 
+// String describes the Authorization for debugging. The password and the tokens are masked, so that it is safe to log.
 func (auth *Authorization) String() string {
-	return fmt.Sprintf("Authorization{\n  Username: '%s'\n  Password: '********',\n  AccessToken: '%s',\n  RefreshToken: '%s',\n  TokenExpires: %d\n}\n", auth.Username, auth.AccessToken, auth.RefreshToken, auth.TokenExpires)
+	auth.Lock()
+	defer auth.Unlock()
+	return fmt.Sprintf("Authorization{\n  Username: '%s'\n  Password: '%s',\n  AccessToken: '%s',\n  RefreshToken: '%s',\n  TokenExpires: %d\n}\n", auth.Username, mask(auth.Password), mask(auth.AccessToken), mask(auth.RefreshToken), auth.TokenExpires)
+}
+
+// mask hides a secret, only revealing whether it is set.
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
 }
 
 // tokenResponse encapulates the Authorization response from UltraDNS
@@ -1,10 +1,15 @@
 package ultradns
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,10 +23,11 @@ const validPassword = "secretcode"
 func ultradnsAuthMockServer(t *testing.T) *httptest.Server {
 	// Container for the response
 	var resp string
-	respCode := 200
+	var respCode int
 	var refreshToken string
 
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respCode = 200
 		if r.RequestURI == "/authorization/token" {
 			err := r.ParseForm()
 			assert.NoError(t, err)
@@ -138,3 +144,302 @@ func TestAuthorizationFailed(t *testing.T) {
 	err := auth.Authorize(client)
 	assert.Error(t, err, "Failed authorization didn't return an error")
 }
+
+func TestAuthorizeContextCanceled(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := auth.AuthorizeContext(ctx, client)
+	assert.True(t, errors.Is(err, context.Canceled), "expected canceled, got %v", err)
+	assert.Equal(t, "", auth.AccessToken)
+}
+
+// Test that a seeded refresh token is used without needing a username/password.
+func TestSeededRefreshTokenAuthorization(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	// Obtain a refresh token the server knows about.
+	first := NewAuthorization(validUsername, validPassword)
+	first.BaseURL = server.URL
+	assert.NoError(t, first.Authorize(client))
+
+	auth := NewAuthorization("", "")
+	auth.BaseURL = server.URL
+	auth.RefreshToken = first.RefreshToken
+
+	assert.NoError(t, auth.Authorize(client))
+	assert.NotEqual(t, "", auth.AccessToken)
+	assert.NotEqual(t, first.RefreshToken, auth.RefreshToken)
+}
+
+// Test that a rejected refresh token falls back to the username/password.
+func TestRejectedRefreshTokenFallsBackToPassword(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	auth.RefreshToken = "stale"
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	assert.NoError(t, auth.Authorize(client))
+	assert.NotEqual(t, "", auth.AccessToken)
+	assert.NotEqual(t, "stale", auth.RefreshToken)
+}
+
+// Test that the errors of both grants are reported when both fail.
+func TestRejectedRefreshTokenAndPassword(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+
+	auth := NewAuthorization("EVILHACKER", "MWAHAHAHA")
+	auth.BaseURL = server.URL
+	auth.RefreshToken = "stale"
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	err := auth.Authorize(client)
+	authErr := &AuthorizationError{}
+	if assert.True(t, errors.As(err, &authErr), "expected an AuthorizationError, got %v", err) {
+		assert.Error(t, authErr.RefreshTokenErr)
+		assert.Error(t, authErr.PasswordErr)
+	}
+	// The underlying UltraDNS error is still reachable.
+	errorResponse := ErrorResponse{}
+	assert.True(t, errors.As(err, &errorResponse))
+	assert.Equal(t, 60001, errorResponse.ErrorCode())
+}
+
+// closeCountingTransport counts how many of the response bodies it returned were closed.
+type closeCountingTransport struct {
+	responses int32
+	closed    int32
+}
+
+func (t *closeCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&t.responses, 1)
+	resp.Body = &countingBody{ReadCloser: resp.Body, closed: &t.closed}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	closed *int32
+}
+
+func (b *countingBody) Close() error {
+	atomic.AddInt32(b.closed, 1)
+	return b.ReadCloser.Close()
+}
+
+// Test that the bodies of rejected grants are closed as well as those of successful ones.
+func TestRejectedGrantsCloseTheirBodies(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+
+	for _, username := range []string{validUsername, "EVILHACKER"} {
+		auth := NewAuthorization(username, validPassword)
+		auth.BaseURL = server.URL
+		auth.RefreshToken = "stale"
+		transport := &closeCountingTransport{}
+		auth.Authorize(&http.Client{Timeout: 1 * time.Second, Transport: transport})
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&transport.responses), username)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&transport.closed), username)
+	}
+}
+
+// Test that a rejected refresh token without a username/password returns the refresh error.
+func TestRejectedRefreshTokenWithoutPassword(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+
+	auth := NewAuthorization("", "")
+	auth.BaseURL = server.URL
+	auth.RefreshToken = "stale"
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	err := auth.Authorize(client)
+	errorResponse := ErrorResponse{}
+	if assert.True(t, errors.As(err, &errorResponse)) {
+		assert.Equal(t, "invalid_grant", errorResponse.ErrorType())
+	}
+}
+
+// Defines a mock token endpoint that counts token requests and answers them slowly, so that concurrent callers
+// overlap.
+func countingAuthMockServer(calls *int32, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		w.Write([]byte(successResponse(fmt.Sprintf("%0x", rand.Int63()))))
+	}))
+}
+
+// Test that concurrent authorizations share a single token request.
+func TestConcurrentAuthorizationIsSingleFlight(t *testing.T) {
+	var calls int32
+	server := countingAuthMockServer(&calls, 50*time.Millisecond)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	for round := 1; round <= 3; round++ {
+		var wg sync.WaitGroup
+		tokens := make([]string, 50)
+		for i := range tokens {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				token, err := auth.Token(client)
+				assert.NoError(t, err)
+				tokens[i] = token
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(round), atomic.LoadInt32(&calls))
+		for _, token := range tokens {
+			assert.Equal(t, tokens[0], token)
+		}
+
+		// Expire the token so that the next round has to refresh it.
+		auth.Lock()
+		auth.TokenExpires = 0
+		auth.Unlock()
+	}
+}
+
+// Test that a waiter gives up when its own context is done, without affecting the in-flight request.
+func TestTokenWaiterContextCanceled(t *testing.T) {
+	var calls int32
+	server := countingAuthMockServer(&calls, 200*time.Millisecond)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	leaderErr := make(chan error)
+	go func() {
+		_, err := auth.Token(client)
+		leaderErr <- err
+	}()
+	// Give the leader time to start its request.
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := auth.TokenContext(ctx, client)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+
+	assert.NoError(t, <-leaderErr)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// Test that waiters retry when the caller making the token request gave up.
+func TestTokenWaitersRetryAbandonedRequest(t *testing.T) {
+	var calls int32
+	server := countingAuthMockServer(&calls, 100*time.Millisecond)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := auth.TokenContext(ctx, client)
+		leaderErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := auth.Token(client)
+			assert.NoError(t, err)
+			assert.NotEqual(t, "", token)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.True(t, errors.Is(<-leaderErr, context.Canceled))
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// Test that invalidating the current token forces a new token request, but a stale token is ignored.
+func TestInvalidate(t *testing.T) {
+	var calls int32
+	server := countingAuthMockServer(&calls, 0)
+	defer server.Close()
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	first, err := auth.Token(client)
+	assert.NoError(t, err)
+
+	auth.Invalidate("stale")
+	token, err := auth.Token(client)
+	assert.NoError(t, err)
+	assert.Equal(t, first, token)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	auth.Invalidate(first)
+	token, err = auth.Token(client)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, token)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// Test that String doesn't reveal the password or the tokens.
+func TestAuthorizationStringMasksSecrets(t *testing.T) {
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.AccessToken = "access-secret"
+	auth.RefreshToken = "refresh-secret"
+
+	s := auth.String()
+	assert.Contains(t, s, validUsername)
+	assert.NotContains(t, s, validPassword)
+	assert.NotContains(t, s, "access-secret")
+	assert.NotContains(t, s, "refresh-secret")
+}
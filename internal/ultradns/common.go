@@ -2,29 +2,53 @@
 package ultradns
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
 )
 
 // GetError checks the response for HTTP errors. If the status code is >= 400, it attempts to parse the response body
-// to create the proper error struct.
+// to create the proper error struct, and returns it wrapped in an *apierror.Error that classifies it and records the
+// request.
 // If no error is detected, returns nil.
 func GetError(response *http.Response) error {
 	if response.StatusCode < 400 {
 		return nil
 	}
+	return newAPIError(response, parseError(response))
+}
 
+// parseError reads the error from the body of a failed response.
+func parseError(response *http.Response) error {
 	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return fmt.Errorf("API call returned HTTP Status Code %d. Unable to read body of response", response.StatusCode)
 	}
 
-	errorJSON := ErrorResponse{}
-	if err := json.Unmarshal(bodyBytes, &errorJSON); err != nil {
-		return fmt.Errorf("API call returned HTTP Status Code %d. JSON parsing failed for body '%s'", response.StatusCode, string(bodyBytes))
+	// Some endpoints report several errors at once, as an array of error objects.
+	if trimmed := bytes.TrimSpace(bodyBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		errorList := ErrorList{}
+		if err := json.Unmarshal(trimmed, &errorList); err == nil {
+			return errorList
+		}
+	} else {
+		errorJSON := ErrorResponse{}
+		if err := json.Unmarshal(bodyBytes, &errorJSON); err == nil {
+			return errorJSON
+		}
 	}
+	return fmt.Errorf("API call returned HTTP Status Code %d. JSON parsing failed for body '%s'", response.StatusCode, string(bodyBytes))
+}
 
-	return errorJSON
+// newAPIError wraps err, parsed from the response, in an *apierror.Error.
+func newAPIError(response *http.Response, err error) *apierror.Error {
+	method, url := "", ""
+	if response.Request != nil {
+		method, url = response.Request.Method, response.Request.URL.String()
+	}
+	return apierror.New(method, url, response.StatusCode, err)
 }
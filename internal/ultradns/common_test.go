@@ -0,0 +1,92 @@
+package ultradns
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func errorResponse(method string, url string, statusCode int, body string) *http.Response {
+	req, _ := http.NewRequest(method, url, nil)
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+func TestGetErrorSuccess(t *testing.T) {
+	assert.NoError(t, GetError(errorResponse("GET", "https://api.ultradns.com/status", 200, `{}`)))
+}
+
+func TestGetErrorClassifiesResponse(t *testing.T) {
+	resp := errorResponse("DELETE", "https://api.ultradns.com/zones/example.com.", 404, `{"errorCode":1801,"errorMessage":"Zone does not exist in the system."}`)
+	err := GetError(resp)
+
+	assert.EqualError(t, err, "1801: Zone does not exist in the system.")
+	assert.True(t, errors.Is(err, apierror.NotFound))
+	apiErr := &apierror.Error{}
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "DELETE", apiErr.Method)
+		assert.Equal(t, "https://api.ultradns.com/zones/example.com.", apiErr.URL)
+		assert.Equal(t, 404, apiErr.StatusCode)
+	}
+	errorResponse := ErrorResponse{}
+	assert.True(t, errors.As(err, &errorResponse))
+	assert.Equal(t, 1801, errorResponse.ErrorCode())
+}
+
+func TestGetErrorInvalidJSON(t *testing.T) {
+	err := GetError(errorResponse("GET", "https://api.ultradns.com/status", 502, `<html>Bad Gateway</html>`))
+	assert.True(t, errors.Is(err, apierror.ServerError))
+	assert.Contains(t, err.Error(), "502")
+}
+
+// Test that error responses without a message don't panic.
+func TestErrorResponseWithoutMessage(t *testing.T) {
+	assert.Equal(t, "unknown error", ErrorResponse{}.Error())
+	assert.Equal(t, "42: unknown error", ErrorResponse{ErrorCodeCC: 42}.Error())
+	assert.Equal(t, "invalid_grant", ErrorResponse{ErrorTypeValue: "invalid_grant"}.Error())
+
+	err := GetError(errorResponse("GET", "https://api.ultradns.com/status", 500, `{}`))
+	assert.EqualError(t, err, "unknown error")
+}
+
+func TestGetErrorArray(t *testing.T) {
+	resp := errorResponse("POST", "https://api.ultradns.com/zones/example.com./rrsets/A/www", 400,
+		`[{"errorCode":2111,"errorMessage":"Resource Record already exists."},{"errorCode":55001,"errorMessage":"Invalid TTL."}]`)
+	err := GetError(resp)
+
+	assert.EqualError(t, err, "2111: Resource Record already exists.; 55001: Invalid TTL.")
+	assert.True(t, errors.Is(err, apierror.AlreadyExists))
+	assert.True(t, errors.Is(err, apierror.Validation))
+
+	apiErr := &apierror.Error{}
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 2111, apiErr.Code)
+		if assert.Len(t, apiErr.Details, 2) {
+			assert.Equal(t, 55001, apiErr.Details[1].Code)
+			assert.Equal(t, "Invalid TTL.", apiErr.Details[1].Message)
+		}
+	}
+	errorList := ErrorList{}
+	if assert.True(t, errors.As(err, &errorList)) {
+		assert.Len(t, errorList, 2)
+	}
+	// The first error is found as an ErrorResponse, like for single-object responses.
+	errorResponse := ErrorResponse{}
+	if assert.True(t, errors.As(err, &errorResponse)) {
+		assert.Equal(t, 2111, errorResponse.ErrorCode())
+	}
+}
+
+func TestGetErrorEmptyArray(t *testing.T) {
+	err := GetError(errorResponse("GET", "https://api.ultradns.com/status", 503, ` []`))
+	assert.EqualError(t, err, "unknown error")
+	assert.True(t, errors.Is(err, apierror.ServerError))
+}
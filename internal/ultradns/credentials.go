@@ -0,0 +1,194 @@
+package ultradns
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoCredentials is returned by a CredentialsProvider that has no credentials to offer, e.g. because its
+// environment variables are not set. ChainCredentials moves on to the next provider when it sees it.
+var ErrNoCredentials = errors.New("no UltraDNS credentials found")
+
+// Credentials are what Authorization needs to request tokens: a Username/Password, a RefreshToken, or both.
+type Credentials struct {
+	Username     string
+	Password     string
+	RefreshToken string
+}
+
+// CredentialsProvider supplies credentials when Authorization needs to request a token.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+// CredentialsFunc adapts a function to a CredentialsProvider, e.g. to fetch credentials from a secret manager.
+type CredentialsFunc func(ctx context.Context) (*Credentials, error)
+
+// Credentials calls the function.
+func (f CredentialsFunc) Credentials(ctx context.Context) (*Credentials, error) {
+	return f(ctx)
+}
+
+// ChainCredentials returns the credentials of the first provider that has any.
+type ChainCredentials []CredentialsProvider
+
+// Credentials tries the providers in order, skipping those that return ErrNoCredentials.
+func (chain ChainCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	for _, provider := range chain {
+		credentials, err := provider.Credentials(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return credentials, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// Environment variables read by EnvCredentials and ProfileCredentials.
+const (
+	EnvUsername        = "ULTRADNS_USERNAME"
+	EnvPassword        = "ULTRADNS_PASSWORD"
+	EnvRefreshToken    = "ULTRADNS_REFRESH_TOKEN"
+	EnvProfile         = "ULTRADNS_PROFILE"
+	EnvCredentialsFile = "ULTRADNS_CREDENTIALS_FILE"
+)
+
+// EnvCredentials reads the credentials from the ULTRADNS_USERNAME, ULTRADNS_PASSWORD and ULTRADNS_REFRESH_TOKEN
+// environment variables.
+type EnvCredentials struct{}
+
+// Credentials returns ErrNoCredentials unless a username or a refresh token is set.
+func (EnvCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	credentials := &Credentials{
+		Username:     os.Getenv(EnvUsername),
+		Password:     os.Getenv(EnvPassword),
+		RefreshToken: os.Getenv(EnvRefreshToken),
+	}
+	if credentials.Username == "" && credentials.RefreshToken == "" {
+		return nil, ErrNoCredentials
+	}
+	return credentials, nil
+}
+
+// ProfileCredentials reads the credentials from a named profile of an INI-style file:
+//
+//	[default]
+//	username = api-user
+//	password = secret
+//
+//	[staging]
+//	refresh_token = 0123abcd
+//
+// Blank lines and lines starting with '#' or ';' are ignored.
+type ProfileCredentials struct {
+	// Path is the credentials file. Default is $ULTRADNS_CREDENTIALS_FILE, or ~/.ultradns/credentials.
+	Path string
+
+	// Profile is the section to read. Default is $ULTRADNS_PROFILE, or "default".
+	Profile string
+}
+
+// Credentials reads the profile, returning ErrNoCredentials if the file or the profile does not exist.
+func (p *ProfileCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	profile := p.profile()
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	credentials, err := parseProfile(file, profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return credentials, nil
+}
+
+// path returns the credentials file to read.
+func (p *ProfileCredentials) path() (string, error) {
+	if p.Path != "" {
+		return p.Path, nil
+	}
+	if path := os.Getenv(EnvCredentialsFile); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ultradns", "credentials"), nil
+}
+
+// profile returns the profile to read.
+func (p *ProfileCredentials) profile() string {
+	if p.Profile != "" {
+		return p.Profile
+	}
+	if profile := os.Getenv(EnvProfile); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// parseProfile reads the credentials of one profile from an INI-style file.
+func parseProfile(r io.Reader, profile string) (*Credentials, error) {
+	var credentials *Credentials
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == profile && credentials == nil {
+				credentials = &Credentials{}
+			}
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected 'key = value'", lineNumber)
+		}
+		value := strings.TrimSpace(line[i+1:])
+		switch strings.TrimSpace(line[:i]) {
+		case "username":
+			credentials.Username = value
+		case "password":
+			credentials.Password = value
+		case "refresh_token":
+			credentials.RefreshToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return nil, fmt.Errorf("profile '%s' not found: %w", profile, ErrNoCredentials)
+	}
+	return credentials, nil
+}
+
+// DefaultCredentials returns the environment variables, followed by the given profile of the credentials file. An
+// empty profile falls back to $ULTRADNS_PROFILE, then "default".
+func DefaultCredentials(profile string) CredentialsProvider {
+	return ChainCredentials{EnvCredentials{}, &ProfileCredentials{Profile: profile}}
+}
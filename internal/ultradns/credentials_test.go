@@ -0,0 +1,154 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const profileFile = `
+# Shared UltraDNS credentials
+[default]
+username = superuser
+password = secretcode
+
+; tokens only
+[staging]
+refresh_token = 0123abcd
+`
+
+func TestEnvCredentials(t *testing.T) {
+	defer os.Unsetenv(EnvUsername)
+	defer os.Unsetenv(EnvPassword)
+	os.Unsetenv(EnvUsername)
+	os.Unsetenv(EnvRefreshToken)
+
+	_, err := EnvCredentials{}.Credentials(context.Background())
+	assert.Equal(t, ErrNoCredentials, err)
+
+	os.Setenv(EnvUsername, "superuser")
+	os.Setenv(EnvPassword, "secretcode")
+	credentials, err := EnvCredentials{}.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "superuser", Password: "secretcode"}, credentials)
+}
+
+func TestParseProfile(t *testing.T) {
+	credentials, err := parseProfile(strings.NewReader(profileFile), "default")
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "superuser", Password: "secretcode"}, credentials)
+
+	credentials, err = parseProfile(strings.NewReader(profileFile), "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{RefreshToken: "0123abcd"}, credentials)
+
+	_, err = parseProfile(strings.NewReader(profileFile), "production")
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+
+	_, err = parseProfile(strings.NewReader("[default]\nusername superuser\n"), "default")
+	assert.EqualError(t, err, "line 2: expected 'key = value'")
+}
+
+func TestProfileCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+
+	provider := &ProfileCredentials{Path: path, Profile: "staging"}
+	_, err = provider.Credentials(context.Background())
+	assert.Equal(t, ErrNoCredentials, err)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(profileFile), 0600))
+	credentials, err := provider.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "0123abcd", credentials.RefreshToken)
+}
+
+func TestChainCredentials(t *testing.T) {
+	none := CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+		return nil, ErrNoCredentials
+	})
+	some := CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+		return &Credentials{Username: "superuser"}, nil
+	})
+	broken := CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+		return nil, errors.New("secret manager unavailable")
+	})
+
+	credentials, err := ChainCredentials{none, some, broken}.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "superuser", credentials.Username)
+
+	_, err = ChainCredentials{none, broken, some}.Credentials(context.Background())
+	assert.EqualError(t, err, "secret manager unavailable")
+
+	_, err = ChainCredentials{none}.Credentials(context.Background())
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+// Test that the provider is only consulted when a token is needed, and every time one is.
+func TestAuthorizationResolvesCredentialsLazily(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	calls := 0
+	auth := NewAuthorization("", "")
+	auth.BaseURL = server.URL
+	auth.Credentials = CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+		calls++
+		return &Credentials{Username: validUsername, Password: validPassword}, nil
+	})
+	assert.Equal(t, 0, calls)
+
+	assert.NoError(t, auth.Authorize(client))
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, auth.Authorize(client))
+	assert.Equal(t, 1, calls)
+
+	auth.Lock()
+	auth.TokenExpires = 0
+	auth.Unlock()
+	assert.NoError(t, auth.Authorize(client))
+	assert.Equal(t, 2, calls)
+}
+
+// Test that a refresh token from the provider is used without a username/password.
+func TestAuthorizationUsesProvidedRefreshToken(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+	first := NewAuthorization(validUsername, validPassword)
+	first.BaseURL = server.URL
+	assert.NoError(t, first.Authorize(client))
+
+	auth := NewAuthorization("", "")
+	auth.BaseURL = server.URL
+	auth.Credentials = CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+		return &Credentials{RefreshToken: first.RefreshToken}, nil
+	})
+	assert.NoError(t, auth.Authorize(client))
+	assert.NotEqual(t, first.RefreshToken, auth.RefreshToken)
+}
+
+func TestAuthorizationCredentialsError(t *testing.T) {
+	auth := NewAuthorization("", "")
+	auth.Credentials = ChainCredentials{}
+	err := auth.Authorize(&http.Client{})
+	assert.True(t, errors.Is(err, ErrNoCredentials), "expected ErrNoCredentials, got %v", err)
+}
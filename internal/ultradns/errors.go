@@ -1,12 +1,14 @@
 package ultradns
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ErrorResponse is a representation of the UltraDNS' API JSON error messages.
 // API calls can return this type as an error.
 // UltraDNS' API return values vary between snake and camel case. This attempts to handle that.
 type ErrorResponse struct {
-	error
 	// Numerical code
 	ErrorCodeCC int `json:"errorCode"`
 	ErrorCodeSC int `json:"error_code"`
@@ -61,7 +63,77 @@ func (e ErrorResponse) Error() string {
 		return e.ErrorDescription()
 	case e.ErrorMessage() != "":
 		return fmt.Sprintf("%d: %s", e.ErrorCode(), e.ErrorMessage())
+	case e.ErrorType() != "":
+		return e.ErrorType()
+	case e.ErrorCode() != 0:
+		return fmt.Sprintf("%d: unknown error", e.ErrorCode())
 	default:
-		panic(e)
+		return "unknown error"
 	}
 }
+
+// ErrorList holds the errors of a response that reported them as a JSON array rather than a single object.
+type ErrorList []ErrorResponse
+
+// Error joins the messages of all errors.
+func (list ErrorList) Error() string {
+	if len(list) == 0 {
+		return list.first().Error()
+	}
+	messages := make([]string, len(list))
+	for i, e := range list {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorCode returns the code of the first error.
+func (list ErrorList) ErrorCode() int {
+	return list.first().ErrorCode()
+}
+
+// ErrorMessage returns the message of the first error.
+func (list ErrorList) ErrorMessage() string {
+	return list.first().ErrorMessage()
+}
+
+// Errors returns the individual errors.
+func (list ErrorList) Errors() []error {
+	errs := make([]error, len(list))
+	for i, e := range list {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Unwrap returns the first error, so that errors.As finds an ErrorResponse in a list as well.
+func (list ErrorList) Unwrap() error {
+	return list.first()
+}
+
+// first returns the first error, or an empty ErrorResponse for an empty list.
+func (list ErrorList) first() ErrorResponse {
+	if len(list) == 0 {
+		return ErrorResponse{}
+	}
+	return list[0]
+}
+
+// AuthorizationError is returned when authorizing with the refresh token was rejected, and the fallback to the
+// username/password failed as well.
+type AuthorizationError struct {
+	// RefreshTokenErr is the error returned for the refresh token grant
+	RefreshTokenErr error
+	// PasswordErr is the error returned for the password grant
+	PasswordErr error
+}
+
+// Error is the interface for the error type.
+func (e *AuthorizationError) Error() string {
+	return fmt.Sprintf("authorization failed: refresh token rejected (%s), password rejected (%s)", e.RefreshTokenErr, e.PasswordErr)
+}
+
+// Unwrap returns the error of the password grant, the last one attempted.
+func (e *AuthorizationError) Unwrap() error {
+	return e.PasswordErr
+}
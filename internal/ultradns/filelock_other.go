@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package ultradns
+
+import "os"
+
+// tryLockFile always succeeds: without flock(2), FileTokenStore does not lock across processes. Tokens are still
+// shared, but concurrent processes may each request their own.
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile does nothing.
+func unlockFile(file *os.File) error {
+	return nil
+}
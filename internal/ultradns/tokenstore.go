@@ -0,0 +1,166 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Tokens are the tokens persisted by a TokenStore.
+type Tokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	// TokenExpires is the Unix time at which the AccessToken should no longer be used.
+	TokenExpires int64 `json:"tokenExpires"`
+}
+
+// valid returns true if the AccessToken has not expired yet.
+func (tokens *Tokens) valid() bool {
+	return tokens.AccessToken != "" && time.Now().Unix() < tokens.TokenExpires
+}
+
+// TokenStore persists tokens beyond the lifetime of an Authorization, so that several Authorizations, possibly in
+// different processes, can share them instead of each requesting their own.
+type TokenStore interface {
+	// Load returns the stored tokens, or nil if there are none.
+	Load() (*Tokens, error)
+
+	// Save replaces the stored tokens.
+	Save(tokens *Tokens) error
+
+	// Lock blocks until no one else sharing the store holds the lock, or until the context is done. Authorization
+	// holds it while requesting tokens, so that only one of the processes sharing the store requests them at a time.
+	// The returned function releases the lock.
+	Lock(ctx context.Context) (unlock func(), err error)
+}
+
+// MemoryTokenStore is a TokenStore that keeps the tokens in memory, e.g. to share them between several
+// Authorizations of one process.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens *Tokens
+	lock   chan struct{}
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{lock: make(chan struct{}, 1)}
+}
+
+// Load returns a copy of the stored tokens, or nil if there are none.
+func (store *MemoryTokenStore) Load() (*Tokens, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.tokens == nil {
+		return nil, nil
+	}
+	tokens := *store.tokens
+	return &tokens, nil
+}
+
+// Save stores a copy of the tokens.
+func (store *MemoryTokenStore) Save(tokens *Tokens) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	saved := *tokens
+	store.tokens = &saved
+	return nil
+}
+
+// Lock acquires the store's lock.
+func (store *MemoryTokenStore) Lock(ctx context.Context) (func(), error) {
+	select {
+	case store.lock <- struct{}{}:
+		return func() { <-store.lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FileTokenStore is a TokenStore that keeps the tokens in a JSON file, e.g. to share them between short-lived
+// processes. The file is only readable by its owner and is replaced atomically. Locking uses flock(2) on a separate
+// "<path>.lock" file where available.
+type FileTokenStore struct {
+	Path string
+}
+
+// NewFileTokenStore returns a FileTokenStore keeping the tokens at path. The file is created on the first Save.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// Load reads the tokens from the file, returning nil if it does not exist yet.
+func (store *FileTokenStore) Load() (*Tokens, error) {
+	data, err := ioutil.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens := &Tokens{}
+	if err = json.Unmarshal(data, tokens); err != nil {
+		return nil, fmt.Errorf("token store %s: %w", store.Path, err)
+	}
+	return tokens, nil
+}
+
+// Save writes the tokens to a temporary file next to the store's file, which then replaces it. Readers never see a
+// partially written file.
+func (store *FileTokenStore) Save(tokens *Tokens) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	// TempFile creates the file with mode 0600.
+	tmp, err := ioutil.TempFile(filepath.Dir(store.Path), filepath.Base(store.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), store.Path)
+}
+
+// Lock acquires an exclusive lock on "<path>.lock", polling until it is available or the context is done.
+func (store *FileTokenStore) Lock(ctx context.Context) (func(), error) {
+	file, err := os.OpenFile(store.Path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			return func() {
+				unlockFile(file)
+				file.Close()
+			}, nil
+		}
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
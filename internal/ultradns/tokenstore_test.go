@@ -0,0 +1,184 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempTokenFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "tokenstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "tokens.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestFileTokenStoreSaveAndLoad(t *testing.T) {
+	path, cleanup := tempTokenFile(t)
+	defer cleanup()
+	store := NewFileTokenStore(path)
+
+	tokens, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, tokens)
+
+	saved := &Tokens{AccessToken: "access", RefreshToken: "refresh", TokenExpires: 1234}
+	assert.NoError(t, store.Save(saved))
+	tokens, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, saved, tokens)
+
+	info, err := os.Stat(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+	// No temporary files are left behind.
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	assert.Len(t, files, 1)
+}
+
+func TestFileTokenStoreLoadCorrupt(t *testing.T) {
+	path, cleanup := tempTokenFile(t)
+	defer cleanup()
+	assert.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+	_, err := NewFileTokenStore(path).Load()
+	assert.Error(t, err)
+}
+
+func TestFileTokenStoreLockIsExclusive(t *testing.T) {
+	path, cleanup := tempTokenFile(t)
+	defer cleanup()
+	// Separate stores open the lock file separately, like separate processes would.
+	first, second := NewFileTokenStore(path), NewFileTokenStore(path)
+
+	unlock, err := first.Lock(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = second.Lock(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+
+	unlock()
+	unlock, err = second.Lock(context.Background())
+	assert.NoError(t, err)
+	unlock()
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+	tokens, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, tokens)
+
+	saved := &Tokens{AccessToken: "access", RefreshToken: "refresh", TokenExpires: 1234}
+	assert.NoError(t, store.Save(saved))
+	// The store keeps its own copy.
+	saved.AccessToken = "changed"
+	tokens, _ = store.Load()
+	assert.Equal(t, "access", tokens.AccessToken)
+
+	unlock, err := store.Lock(context.Background())
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = store.Lock(ctx)
+	assert.Error(t, err)
+	unlock()
+}
+
+// Test that Authorizations sharing a store, as separate processes would, request a token only once.
+func TestAuthorizationsShareStoredTokens(t *testing.T) {
+	path, cleanup := tempTokenFile(t)
+	defer cleanup()
+	var calls int32
+	server := countingAuthMockServer(&calls, 20*time.Millisecond)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			auth := NewAuthorization(validUsername, validPassword)
+			auth.BaseURL = server.URL
+			auth.Store = NewFileTokenStore(path)
+			token, err := auth.Token(client)
+			assert.NoError(t, err)
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, token := range tokens {
+		assert.Equal(t, tokens[0], token)
+	}
+}
+
+// Test that an expired stored access token is renewed with the stored refresh token.
+func TestAuthorizationUsesStoredRefreshToken(t *testing.T) {
+	server := ultradnsAuthMockServer(t)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+	store := NewMemoryTokenStore()
+
+	first := NewAuthorization(validUsername, validPassword)
+	first.BaseURL = server.URL
+	first.Store = store
+	assert.NoError(t, first.Authorize(client))
+	stored, _ := store.Load()
+	stored.TokenExpires = 0
+	store.Save(stored)
+
+	// Without a username/password, only the stored refresh token can succeed.
+	auth := NewAuthorization("", "")
+	auth.BaseURL = server.URL
+	auth.Store = store
+	token, err := auth.Token(client)
+	assert.NoError(t, err)
+	assert.NotEqual(t, stored.AccessToken, token)
+
+	renewed, _ := store.Load()
+	assert.Equal(t, token, renewed.AccessToken)
+	assert.NotEqual(t, stored.RefreshToken, renewed.RefreshToken)
+}
+
+// Test that an invalidated token is not loaded from the store again.
+func TestAuthorizationSkipsRejectedStoredToken(t *testing.T) {
+	var calls int32
+	server := countingAuthMockServer(&calls, 0)
+	defer server.Close()
+	client := &http.Client{
+		Timeout: 1 * time.Second,
+	}
+
+	auth := NewAuthorization(validUsername, validPassword)
+	auth.BaseURL = server.URL
+	auth.Store = NewMemoryTokenStore()
+	first, err := auth.Token(client)
+	assert.NoError(t, err)
+
+	auth.Invalidate(first)
+	token, err := auth.Token(client)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, token)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
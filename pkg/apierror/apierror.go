@@ -0,0 +1,222 @@
+// Package apierror classifies the errors returned by the UltraDNS API, so that callers can handle them without
+// matching on error codes or messages:
+//
+//	_, err := apiConn.Zones().Get(ctx, "example.com.")
+//	if errors.Is(err, apierror.NotFound) {
+//		// create it
+//	}
+//
+// Every error returned for an API response wraps an *Error, which carries the Kind along with the request method,
+// URL and status code.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind is the category of an API error. The kinds are errors themselves, so they can be used as the target of
+// errors.Is.
+type Kind int
+
+// Kinds of API errors.
+const (
+	// Unknown is an error that fits no other kind, e.g. an unexpected 3xx or 4xx status.
+	Unknown Kind = iota
+	// NotFound means the zone, record set or other resource does not exist.
+	NotFound
+	// AlreadyExists means the resource to create exists already.
+	AlreadyExists
+	// Unauthorized means the credentials or the token were rejected, or the account lacks the permission.
+	Unauthorized
+	// RateLimited means the request was throttled with 429 Too Many Requests.
+	RateLimited
+	// Validation means the request was malformed or its content invalid.
+	Validation
+	// Conflict means the request conflicts with the current state of the resource.
+	Conflict
+	// ServerError means UltraDNS failed to process the request.
+	ServerError
+)
+
+var kindNames = map[Kind]string{
+	Unknown:       "unknown error",
+	NotFound:      "not found",
+	AlreadyExists: "already exists",
+	Unauthorized:  "unauthorized",
+	RateLimited:   "rate limited",
+	Validation:    "validation failed",
+	Conflict:      "conflict",
+	ServerError:   "server error",
+}
+
+// String returns a short description of the kind, e.g. "not found".
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return kindNames[Unknown]
+}
+
+// Error is the interface for the error type.
+func (k Kind) Error() string {
+	return k.String()
+}
+
+// codeKinds maps UltraDNS error codes to kinds. The code takes precedence over the HTTP status, which UltraDNS does
+// not always choose accurately, e.g. 400 for a zone that doesn't exist.
+var codeKinds = map[int]Kind{
+	// Zone does not exist in the system.
+	1801: NotFound,
+	// Zone already exists in the system.
+	1802: AlreadyExists,
+	// Resource record with these attributes already exists in the system.
+	2111: AlreadyExists,
+	// Cannot find resource record data for the input zone, record type and owner combination.
+	56001: NotFound,
+	// Invalid, expired or missing credentials or token.
+	60001: Unauthorized,
+	60004: Unauthorized,
+	// Data not found.
+	70002: NotFound,
+}
+
+// Classify returns the kind of the UltraDNS error code, falling back to the kind of the HTTP status code.
+func Classify(code int, statusCode int) Kind {
+	if kind, ok := codeKinds[code]; ok {
+		return kind
+	}
+	switch {
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return Validation
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return Unauthorized
+	case statusCode == http.StatusNotFound:
+		return NotFound
+	case statusCode == http.StatusConflict:
+		return Conflict
+	case statusCode == http.StatusTooManyRequests:
+		return RateLimited
+	case statusCode >= 500:
+		return ServerError
+	default:
+		return Unknown
+	}
+}
+
+// Error is an error response of the UltraDNS API.
+type Error struct {
+	Kind Kind
+
+	// Method and URL of the request that failed.
+	Method string
+	URL    string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Code is the UltraDNS error code, or 0 if the response had none. If the response reported several errors, it is
+	// the code of the first one.
+	Code int
+
+	// Details lists every error the response reported. UltraDNS reports several at once e.g. when a request fails
+	// more than one validation.
+	Details []Detail
+
+	// Err is the error parsed from the response body.
+	Err error
+}
+
+// Detail is a single error reported by a response.
+type Detail struct {
+	Kind    Kind
+	Code    int
+	Message string
+}
+
+// coder is implemented by errors parsed from a response body that carries an UltraDNS error code.
+type coder interface {
+	ErrorCode() int
+	ErrorMessage() string
+}
+
+// multiError is implemented by errors holding all the errors of a response that reported several.
+type multiError interface {
+	Errors() []error
+}
+
+// New returns the *Error for err, which was parsed from a response with the given status code to a request with the
+// given method and URL. The UltraDNS error codes are taken from err if it has ErrorCode() and ErrorMessage() methods,
+// or from each of its errors if it has an Errors() []error method.
+func New(method string, url string, statusCode int, err error) *Error {
+	var errs []error
+	var multi multiError
+	if errors.As(err, &multi) {
+		errs = multi.Errors()
+	} else {
+		errs = []error{err}
+	}
+
+	apiErr := &Error{
+		Kind:       Classify(0, statusCode),
+		Method:     method,
+		URL:        url,
+		StatusCode: statusCode,
+		Err:        err,
+	}
+	for _, e := range errs {
+		var c coder
+		if !errors.As(e, &c) {
+			continue
+		}
+		apiErr.Details = append(apiErr.Details, Detail{
+			Kind:    Classify(c.ErrorCode(), statusCode),
+			Code:    c.ErrorCode(),
+			Message: c.ErrorMessage(),
+		})
+	}
+	if len(apiErr.Details) > 0 {
+		apiErr.Kind = apiErr.Details[0].Kind
+		apiErr.Code = apiErr.Details[0].Code
+	}
+	return apiErr
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.String()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error parsed from the response body.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error, or any of its Details, is of the given Kind.
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(Kind)
+	if !ok {
+		return false
+	}
+	if kind == e.Kind {
+		return true
+	}
+	for _, detail := range e.Details {
+		if kind == detail.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// KindOf returns the kind of the first *Error in err's chain, or Unknown if there is none.
+func KindOf(err error) Kind {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return Unknown
+}
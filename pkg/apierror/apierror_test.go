@@ -0,0 +1,102 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codedError struct {
+	code int
+}
+
+func (e codedError) Error() string {
+	return fmt.Sprintf("%d: failed", e.code)
+}
+
+func (e codedError) ErrorCode() int {
+	return e.code
+}
+
+func (e codedError) ErrorMessage() string {
+	return "failed"
+}
+
+type codedErrors []error
+
+func (e codedErrors) Error() string {
+	return "several errors"
+}
+
+func (e codedErrors) Errors() []error {
+	return e
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		code       int
+		statusCode int
+		kind       Kind
+	}{
+		{1801, 400, NotFound},
+		{1802, 400, AlreadyExists},
+		{2111, 400, AlreadyExists},
+		{56001, 404, NotFound},
+		{60001, 400, Unauthorized},
+		{60004, 400, Unauthorized},
+		{0, 400, Validation},
+		{0, 401, Unauthorized},
+		{0, 403, Unauthorized},
+		{0, 404, NotFound},
+		{0, 409, Conflict},
+		{0, 429, RateLimited},
+		{0, 500, ServerError},
+		{0, 503, ServerError},
+		{0, 418, Unknown},
+	} {
+		assert.Equal(t, tc.kind, Classify(tc.code, tc.statusCode), "code %d, status %d", tc.code, tc.statusCode)
+	}
+}
+
+func TestErrorIsAndAs(t *testing.T) {
+	cause := codedError{1801}
+	err := fmt.Errorf("getting zone: %w", New("GET", "https://api.ultradns.com/zones/example.com.", 404, cause))
+
+	assert.True(t, errors.Is(err, NotFound))
+	assert.False(t, errors.Is(err, AlreadyExists))
+	assert.Equal(t, NotFound, KindOf(err))
+	assert.EqualError(t, err, "getting zone: 1801: failed")
+
+	apiErr := &Error{}
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "GET", apiErr.Method)
+		assert.Equal(t, 404, apiErr.StatusCode)
+		assert.Equal(t, 1801, apiErr.Code)
+	}
+	// The parsed error is still reachable.
+	coded := codedError{}
+	assert.True(t, errors.As(err, &coded))
+}
+
+func TestErrorDetails(t *testing.T) {
+	err := New("POST", "https://api.ultradns.com/zones", 400, codedErrors{codedError{1802}, errors.New("not coded"), codedError{0}})
+
+	assert.Equal(t, AlreadyExists, err.Kind)
+	assert.Equal(t, 1802, err.Code)
+	assert.Equal(t, []Detail{
+		{Kind: AlreadyExists, Code: 1802, Message: "failed"},
+		{Kind: Validation, Code: 0, Message: "failed"},
+	}, err.Details)
+	// Any of the details matches.
+	assert.True(t, errors.Is(err, AlreadyExists))
+	assert.True(t, errors.Is(err, Validation))
+	assert.False(t, errors.Is(err, NotFound))
+}
+
+func TestKindOfOtherErrors(t *testing.T) {
+	assert.Equal(t, Unknown, KindOf(errors.New("connection refused")))
+	assert.Equal(t, Unknown, KindOf(nil))
+	assert.Equal(t, "not found", NotFound.Error())
+}
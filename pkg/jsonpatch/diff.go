@@ -0,0 +1,113 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Diff returns a patch that turns from into to. Both are compared in their JSON form, so any values that marshal to
+// JSON can be diffed, e.g. the current and desired ultradns.RRSet.
+//
+// Changed members are replaced as deep in the document as possible. Arrays are diffed by their longest common
+// subsequence, so inserting or removing a single element produces a single operation instead of rewriting the
+// remainder of the array.
+func Diff(from interface{}, to interface{}) (Patch, error) {
+	fromNode, err := normalize(from)
+	if err != nil {
+		return nil, err
+	}
+	toNode, err := normalize(to)
+	if err != nil {
+		return nil, err
+	}
+	return diff(Patch{}, "", fromNode, toNode), nil
+}
+
+// diff appends the operations turning from into to, both located at path.
+func diff(patch Patch, path string, from interface{}, to interface{}) Patch {
+	if reflect.DeepEqual(from, to) {
+		return patch
+	}
+	switch fromNode := from.(type) {
+	case map[string]interface{}:
+		if toNode, ok := to.(map[string]interface{}); ok {
+			return diffObjects(patch, path, fromNode, toNode)
+		}
+	case []interface{}:
+		if toNode, ok := to.([]interface{}); ok {
+			return diffArrays(patch, path, fromNode, toNode)
+		}
+	}
+	return patch.Replace(path, to)
+}
+
+// diffObjects diffs two objects member by member, in sorted order to keep patches stable.
+func diffObjects(patch Patch, path string, from map[string]interface{}, to map[string]interface{}) Patch {
+	for _, key := range sortedKeys(from) {
+		if _, ok := to[key]; !ok {
+			patch = patch.Remove(path + "/" + EscapeToken(key))
+		}
+	}
+	for _, key := range sortedKeys(to) {
+		memberPath := path + "/" + EscapeToken(key)
+		if fromValue, ok := from[key]; ok {
+			patch = diff(patch, memberPath, fromValue, to[key])
+		} else {
+			patch = patch.Add(memberPath, to[key])
+		}
+	}
+	return patch
+}
+
+// diffArrays diffs two arrays using their longest common subsequence. Elements that are not part of it are diffed
+// in place when both arrays have one at the same position, and otherwise removed or added.
+func diffArrays(patch Patch, path string, from []interface{}, to []interface{}) Patch {
+	// lcs[i][j] is the length of the longest common subsequence of from[i:] and to[j:].
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			switch {
+			case reflect.DeepEqual(from[i], to[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// index tracks the position in the array as it is being patched.
+	i, j, index := 0, 0, 0
+	for i < len(from) || j < len(to) {
+		elementPath := path + "/" + strconv.Itoa(index)
+		switch {
+		case i < len(from) && j < len(to) && reflect.DeepEqual(from[i], to[j]):
+			i, j, index = i+1, j+1, index+1
+		case i < len(from) && j < len(to) && lcs[i+1][j+1] == lcs[i][j]:
+			patch = diff(patch, elementPath, from[i], to[j])
+			i, j, index = i+1, j+1, index+1
+		case j < len(to) && (i == len(from) || lcs[i][j+1] >= lcs[i+1][j]):
+			patch = patch.Add(elementPath, to[j])
+			j, index = j+1, index+1
+		default:
+			patch = patch.Remove(elementPath)
+			i++
+		}
+	}
+	return patch
+}
+
+// sortedKeys returns the keys of an object in sorted order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
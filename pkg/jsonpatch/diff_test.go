@@ -0,0 +1,65 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertDiff checks that Diff produces the expected patch, and that applying it turns from into to.
+func assertDiff(t *testing.T, from string, to string, expected string) {
+	var fromValue, toValue interface{}
+	assert.NoError(t, json.Unmarshal([]byte(from), &fromValue))
+	assert.NoError(t, json.Unmarshal([]byte(to), &toValue))
+
+	patch, err := Diff(fromValue, toValue)
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(patch)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(encoded), "diff of %s and %s", from, to)
+
+	patched, err := patch.Apply([]byte(from))
+	assert.NoError(t, err)
+	assert.JSONEq(t, to, string(patched))
+}
+
+func TestDiff(t *testing.T) {
+	assertDiff(t, `{"a":1}`, `{"a":1}`, `[]`)
+	assertDiff(t, `{"a":1,"b":2}`, `{"a":3,"c":4}`,
+		`[{"op":"remove","path":"/b"},{"op":"replace","path":"/a","value":3},{"op":"add","path":"/c","value":4}]`)
+	assertDiff(t, `{"a/b":{"c":1}}`, `{"a/b":{"c":2}}`, `[{"op":"replace","path":"/a~1b/c","value":2}]`)
+	assertDiff(t, `{"a":[1]}`, `{"a":{"x":1}}`, `[{"op":"replace","path":"/a","value":{"x":1}}]`)
+	assertDiff(t, `1`, `"one"`, `[{"op":"replace","path":"","value":"one"}]`)
+}
+
+func TestDiffArrays(t *testing.T) {
+	// Appending, inserting and removing only touch the affected element.
+	assertDiff(t, `[1,2]`, `[1,2,3]`, `[{"op":"add","path":"/2","value":3}]`)
+	assertDiff(t, `[1,3]`, `[1,2,3]`, `[{"op":"add","path":"/1","value":2}]`)
+	assertDiff(t, `[1,2,3]`, `[1,3]`, `[{"op":"remove","path":"/1"}]`)
+	assertDiff(t, `[1,2,3,4]`, `[2,4]`, `[{"op":"remove","path":"/0"},{"op":"remove","path":"/1"}]`)
+	// Changed elements are diffed in place.
+	assertDiff(t, `[{"state":"NORMAL","priority":1},{"state":"NORMAL","priority":2}]`,
+		`[{"state":"NORMAL","priority":1},{"state":"INACTIVE","priority":2}]`,
+		`[{"op":"replace","path":"/1/state","value":"INACTIVE"}]`)
+	assertDiff(t, `[1,2,3]`, `[4,5]`,
+		`[{"op":"replace","path":"/0","value":4},{"op":"replace","path":"/1","value":5},{"op":"remove","path":"/2"}]`)
+	assertDiff(t, `[]`, `[1,2]`, `[{"op":"add","path":"/0","value":1},{"op":"add","path":"/1","value":2}]`)
+}
+
+func TestDiffStructs(t *testing.T) {
+	type info struct {
+		State string `json:"state"`
+	}
+	type pool struct {
+		RData     []string `json:"rdata"`
+		RDataInfo []info   `json:"rdataInfo"`
+	}
+	from := pool{RData: []string{"192.0.2.1", "192.0.2.2"}, RDataInfo: []info{{"NORMAL"}, {"NORMAL"}}}
+	to := pool{RData: []string{"192.0.2.2"}, RDataInfo: []info{{"INACTIVE"}}}
+
+	patch, err := Diff(from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, Patch{}.Remove("/rdata/0").Replace("/rdataInfo/0/state", "INACTIVE").Remove("/rdataInfo/1"), patch)
+}
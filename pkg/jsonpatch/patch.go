@@ -0,0 +1,362 @@
+// Package jsonpatch builds, applies and computes RFC 6902 JSON Patch documents, as accepted by
+// ultradns.APIConnection.JSONPatch.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation names defined by RFC 6902.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Operation is a single JSON Patch operation. Path and From are JSON Pointers, see Pointer.
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// MarshalJSON writes only the members the operation uses. Unlike omitempty, this keeps an explicit null value.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{
+		"op":   op.Op,
+		"path": op.Path,
+	}
+	switch op.Op {
+	case OpAdd, OpReplace, OpTest:
+		out["value"] = op.Value
+	case OpMove, OpCopy:
+		out["from"] = op.From
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads an operation as written by MarshalJSON.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		From  string      `json:"from"`
+		Value interface{} `json:"value"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*op = Operation{Op: raw.Op, Path: raw.Path, From: raw.From, Value: raw.Value}
+	return nil
+}
+
+// Patch is a JSON Patch document: a list of operations applied in order. The builder methods return the extended
+// patch, so they can be chained:
+//
+//	patch := jsonpatch.Patch{}.
+//		Test("/rdata/1", "192.0.2.2").
+//		Remove("/rdata/1")
+type Patch []Operation
+
+// Add appends an "add" operation.
+func (p Patch) Add(path string, value interface{}) Patch {
+	return append(p, Operation{Op: OpAdd, Path: path, Value: value})
+}
+
+// Remove appends a "remove" operation.
+func (p Patch) Remove(path string) Patch {
+	return append(p, Operation{Op: OpRemove, Path: path})
+}
+
+// Replace appends a "replace" operation.
+func (p Patch) Replace(path string, value interface{}) Patch {
+	return append(p, Operation{Op: OpReplace, Path: path, Value: value})
+}
+
+// Move appends a "move" operation.
+func (p Patch) Move(from string, path string) Patch {
+	return append(p, Operation{Op: OpMove, From: from, Path: path})
+}
+
+// Copy appends a "copy" operation.
+func (p Patch) Copy(from string, path string) Patch {
+	return append(p, Operation{Op: OpCopy, From: from, Path: path})
+}
+
+// Test appends a "test" operation, which makes the whole patch fail unless the value at path equals value.
+func (p Patch) Test(path string, value interface{}) Patch {
+	return append(p, Operation{Op: OpTest, Path: path, Value: value})
+}
+
+// Reader returns the patch encoded as JSON, ready to be passed to APIConnection.JSONPatch.
+func (p Patch) Reader() (io.Reader, error) {
+	if p == nil {
+		p = Patch{}
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+// Pointer builds a JSON Pointer from unescaped reference tokens, e.g. Pointer("profile", "rdataInfo", "0") returns
+// "/profile/rdataInfo/0". No tokens refers to the whole document.
+func Pointer(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(EscapeToken(token))
+	}
+	return b.String()
+}
+
+// EscapeToken escapes "~" and "/" in a single reference token.
+func EscapeToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// UnescapeToken reverses EscapeToken.
+func UnescapeToken(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
+
+// ParsePointer splits a JSON Pointer into its unescaped reference tokens.
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer '%s' must start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = UnescapeToken(token)
+	}
+	return tokens, nil
+}
+
+// Apply applies the patch to a JSON document and returns the patched document. The patch is applied atomically:
+// if any operation fails, an error is returned and nothing is changed.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var node interface{}
+	if err := json.Unmarshal(doc, &node); err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		var err error
+		if node, err = op.apply(node); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %s", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(node)
+}
+
+// apply applies a single operation to a decoded document.
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := ParsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case OpAdd:
+		value, err := normalize(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case OpRemove:
+		return remove(doc, path)
+	case OpReplace:
+		value, err := normalize(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case OpMove, OpCopy:
+		from, err := ParsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == OpMove {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, fmt.Errorf("cannot move '%s' into itself", op.From)
+			}
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = normalize(value); err != nil {
+			// Round tripping through JSON gives the copy its own maps and slices.
+			return nil, err
+		}
+		return add(doc, path, value)
+	case OpTest:
+		expected, err := normalize(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			return nil, fmt.Errorf("test failed: value is %v, not %v", actual, expected)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation '%s'", op.Op)
+	}
+}
+
+// normalize converts a Go value to its generic JSON form (maps, slices, float64, ...), for comparing and inserting.
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// get returns the value at path.
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", token)
+			}
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("cannot index into a scalar with '%s'", token)
+		}
+	}
+	return node, nil
+}
+
+// update applies fn to the container holding the last token of path, and returns the document with the updated
+// container. Slices may be reallocated by fn, which is why the containers are rebuilt on the way back up.
+func update(node interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", path[0])
+		}
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = updated
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("cannot index into a scalar with '%s'", path[0])
+	}
+}
+
+// add inserts value at path. Array elements are shifted, object members are replaced.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i := len(c)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("cannot add '%s' to a scalar", token)
+		}
+	})
+}
+
+// remove deletes the value at path, which must exist.
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", token)
+			}
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:i], c[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove '%s' from a scalar", token)
+		}
+	})
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("'%s' is not an array index", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("array index %d is out of bounds", i)
+	}
+	return i, nil
+}
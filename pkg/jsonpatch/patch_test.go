@@ -0,0 +1,85 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointerEscaping(t *testing.T) {
+	assert.Equal(t, "/profile/rdataInfo/0", Pointer("profile", "rdataInfo", "0"))
+	assert.Equal(t, "/a~1b/m~0n", Pointer("a/b", "m~n"))
+	assert.Equal(t, "", Pointer())
+
+	tokens, err := ParsePointer("/a~1b/m~0n/~01")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "m~n", "~1"}, tokens)
+
+	_, err = ParsePointer("no/slash")
+	assert.Error(t, err)
+}
+
+func TestPatchMarshalling(t *testing.T) {
+	patch := Patch{}.
+		Add("/rdata/-", "192.0.2.1").
+		Remove("/rdata/0").
+		Replace("/ttl", nil).
+		Move("/a", "/b").
+		Copy("/b", "/c").
+		Test("/ttl", 300)
+
+	reader, err := patch.Reader()
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op":"add","path":"/rdata/-","value":"192.0.2.1"},
+		{"op":"remove","path":"/rdata/0"},
+		{"op":"replace","path":"/ttl","value":null},
+		{"op":"move","from":"/a","path":"/b"},
+		{"op":"copy","from":"/b","path":"/c"},
+		{"op":"test","path":"/ttl","value":300}
+	]`, string(body))
+
+	decoded := Patch{}
+	assert.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, "/a", decoded[3].From)
+	assert.Equal(t, float64(300), decoded[5].Value)
+}
+
+func TestPatchApply(t *testing.T) {
+	doc := []byte(`{"rdata":["192.0.2.1","192.0.2.2"],"profile":{"rdataInfo":[{"state":"NORMAL"},{"state":"NORMAL"}]},"ttl":300}`)
+
+	patch := Patch{}.
+		Test("/ttl", 300).
+		Add("/rdata/1", "192.0.2.9").
+		Add("/profile/rdataInfo/-", map[string]string{"state": "INACTIVE"}).
+		Replace("/profile/rdataInfo/0/state", "ACTIVE").
+		Remove("/rdata/2").
+		Copy("/ttl", "/oldTTL").
+		Move("/oldTTL", "/profile/ttl")
+
+	patched, err := patch.Apply(doc)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"rdata":["192.0.2.1","192.0.2.9"],"profile":{"ttl":300,"rdataInfo":[{"state":"ACTIVE"},{"state":"NORMAL"},{"state":"INACTIVE"}]},"ttl":300}`, string(patched))
+}
+
+func TestPatchApplyErrors(t *testing.T) {
+	doc := []byte(`{"a":{"b":1},"list":[1,2]}`)
+	for name, patch := range map[string]Patch{
+		"failed test":       Patch{}.Test("/a/b", 2),
+		"missing member":    Patch{}.Remove("/missing"),
+		"replace missing":   Patch{}.Replace("/missing", 1),
+		"index too large":   Patch{}.Add("/list/3", 1),
+		"leading zero":      Patch{}.Remove("/list/01"),
+		"into scalar":       Patch{}.Add("/a/b/c", 1),
+		"move into itself":  Patch{}.Move("/a", "/a/b/c"),
+		"remove everything": Patch{}.Remove(""),
+		"unknown op":        {Operation{Op: "frobnicate", Path: "/a"}},
+	} {
+		_, err := patch.Apply(doc)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,245 @@
+package ultradns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/simplifi/ultradns-go/internal/ultradns"
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+)
+
+// BatchOperation is a single request of a batch.
+type BatchOperation struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// BatchResult is the outcome of a single operation of a batch.
+type BatchResult struct {
+	// Operation is the operation the result belongs to.
+	Operation BatchOperation
+
+	// StatusCode is the HTTP status code UltraDNS reports for the operation.
+	StatusCode int
+
+	// Body is the response body of the operation.
+	Body json.RawMessage
+
+	// Err is set if the operation failed. It is classified like the errors of any other request, see package
+	// apierror.
+	Err error
+}
+
+// Decode decodes the body of the operation's response into v.
+func (result *BatchResult) Decode(v interface{}) error {
+	return json.Unmarshal(result.Body, v)
+}
+
+// batchResponse is a single element of the response to a batch.
+type batchResponse struct {
+	Code json.RawMessage `json:"code"`
+	Body json.RawMessage `json:"body"`
+}
+
+// statusCode parses the code, which UltraDNS sends either as a number or as a string.
+func (resp *batchResponse) statusCode() (int, error) {
+	return strconv.Atoi(strings.Trim(string(resp.Code), `"`))
+}
+
+// Batch collects operations to submit to UltraDNS in a single request. The builder methods validate their operation
+// like the corresponding service methods and return the batch, so they can be chained. The first invalid operation
+// is reported by Send:
+//
+//	results, err := apiConn.NewBatch().
+//		CreateRRSet("example.com.", &www).
+//		DeleteRRSet("example.com.", ultradns.RRTypeCNAME, "old").
+//		Send(ctx)
+type Batch struct {
+	apiConn    *APIConnection
+	operations []BatchOperation
+	err        error
+}
+
+// NewBatch returns an empty batch to be submitted over this connection.
+func (apiConn *APIConnection) NewBatch() *Batch {
+	return &Batch{apiConn: apiConn}
+}
+
+// Operations returns the operations collected so far.
+func (b *Batch) Operations() []BatchOperation {
+	return b.operations
+}
+
+// Add appends an operation. uri is a path like the ones passed to the verb methods; body is marshaled to JSON and
+// may be nil.
+func (b *Batch) Add(method string, uri string, body interface{}) *Batch {
+	b.operations = append(b.operations, BatchOperation{Method: method, URI: uri, Body: body})
+	return b
+}
+
+// fail records the first error of the builder.
+func (b *Batch) fail(err error) *Batch {
+	if b.err == nil {
+		b.err = fmt.Errorf("batch operation %d: %w", len(b.operations), err)
+	}
+	return b
+}
+
+// CreateZone appends the creation of a zone, like ZonesService.Create.
+func (b *Batch) CreateZone(zone *Zone) *Batch {
+	return b.Add("POST", "/zones", zone)
+}
+
+// DeleteZone appends the deletion of a zone, like ZonesService.Delete.
+func (b *Batch) DeleteZone(name string) *Batch {
+	return b.Add("DELETE", zonePath(name), nil)
+}
+
+// CreateRRSet appends the creation of a record set, like RRSetsService.Create.
+func (b *Batch) CreateRRSet(zone string, rrset *RRSet) *Batch {
+	return b.addRRSet("POST", zone, rrset)
+}
+
+// ReplaceRRSet appends the replacement of a record set, like RRSetsService.Replace.
+func (b *Batch) ReplaceRRSet(zone string, rrset *RRSet) *Batch {
+	return b.addRRSet("PUT", zone, rrset)
+}
+
+// PatchRRSet appends a partial update of a record set, like RRSetsService.Patch.
+func (b *Batch) PatchRRSet(zone string, rrset *RRSet) *Batch {
+	return b.addRRSet("PATCH", zone, rrset)
+}
+
+// JSONPatchRRSet appends a JSON Patch of a record set, like RRSetsService.JSONPatch.
+func (b *Batch) JSONPatchRRSet(zone string, rrtype RRType, owner string, patch jsonpatch.Patch) *Batch {
+	if patch == nil {
+		patch = jsonpatch.Patch{}
+	}
+	return b.Add("PATCH", RRSetPath(zone, rrtype, owner), patch)
+}
+
+// DeleteRRSet appends the deletion of a record set, like RRSetsService.Delete.
+func (b *Batch) DeleteRRSet(zone string, rrtype RRType, owner string) *Batch {
+	return b.Add("DELETE", RRSetPath(zone, rrtype, owner), nil)
+}
+
+// addRRSet validates the record set and appends an operation sending it to its path.
+func (b *Batch) addRRSet(method string, zone string, rrset *RRSet) *Batch {
+	if err := rrset.Validate(); err != nil {
+		return b.fail(err)
+	}
+	return b.Add(method, RRSetPath(zone, rrset.RRType, rrset.OwnerName), rrset)
+}
+
+// Send submits the batch and returns the result of every operation, in the order they were added. The returned error
+// is only set if the batch as a whole failed; the errors of individual operations are in their results.
+func (b *Batch) Send(ctx context.Context) ([]BatchResult, error) {
+	resp, err := b.submit(ctx, "/batch")
+	if err != nil {
+		return nil, err
+	}
+	var responses []batchResponse
+	if err = b.apiConn.decodeJSON(resp, &responses); err != nil {
+		return nil, err
+	}
+	return b.results(responses)
+}
+
+// SendAsync submits the batch to be processed asynchronously, waits for its task and returns the result of every
+// operation like Send. Use it for batches too large to be processed within a single request.
+func (b *Batch) SendAsync(ctx context.Context) ([]BatchResult, error) {
+	resp, err := b.submit(ctx, "/batch?async=true")
+	if err != nil {
+		return nil, err
+	}
+	if err = b.apiConn.decodeJSON(resp, nil); err != nil {
+		return nil, err
+	}
+	taskID := TaskID(resp)
+	if taskID == "" {
+		return nil, fmt.Errorf("batch was not accepted as a task (status %d)", resp.StatusCode)
+	}
+
+	tasks := b.apiConn.Tasks()
+	task, err := tasks.Wait(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	var responses []batchResponse
+	if err = tasks.Result(ctx, task, &responses); err != nil {
+		return nil, err
+	}
+	return b.results(responses)
+}
+
+// submit posts the operations to the batch endpoint.
+func (b *Batch) submit(ctx context.Context, path string) (*http.Response, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.operations) == 0 {
+		return nil, fmt.Errorf("batch has no operations")
+	}
+	body, err := encodeJSON(b.operations)
+	if err != nil {
+		return nil, err
+	}
+	return b.apiConn.PostContext(ctx, path, body)
+}
+
+// results maps the responses back to the operations.
+func (b *Batch) results(responses []batchResponse) ([]BatchResult, error) {
+	if len(responses) != len(b.operations) {
+		return nil, fmt.Errorf("batch of %d operations returned %d results", len(b.operations), len(responses))
+	}
+	results := make([]BatchResult, len(responses))
+	for i, response := range responses {
+		operation := b.operations[i]
+		statusCode, err := response.statusCode()
+		if err != nil {
+			return nil, fmt.Errorf("batch operation %d: invalid status code %s", i, response.Code)
+		}
+		results[i] = BatchResult{
+			Operation:  operation,
+			StatusCode: statusCode,
+			Body:       response.Body,
+			Err:        b.operationError(operation, statusCode, response.Body),
+		}
+	}
+	return results, nil
+}
+
+// operationError parses the error of a failed operation the same way as for a standalone request.
+func (b *Batch) operationError(operation BatchOperation, statusCode int, body []byte) error {
+	if statusCode < 400 {
+		return nil
+	}
+	req := &http.Request{Method: operation.Method}
+	req.URL, _ = url.Parse(b.apiConn.BaseURL + operation.URI)
+	if req.URL == nil {
+		req.URL = &url.URL{Path: operation.URI}
+	}
+	return ultradns.GetError(&http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	})
+}
+
+// BatchErr returns the error of the first failed operation, or nil if all succeeded.
+func BatchErr(results []BatchResult) error {
+	for i := range results {
+		if results[i].Err != nil {
+			return fmt.Errorf("batch operation %d (%s %s): %w", i, results[i].Operation.Method, results[i].Operation.URI, results[i].Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+	"github.com/stretchr/testify/assert"
+)
+
+const batchResponseJSON = `[
+	{"code":201,"body":{"message":"Successful"}},
+	{"code":"400","body":[{"errorCode":2111,"errorMessage":"Resource Record already exists."}]},
+	{"code":204}
+]`
+
+func newTestBatch(apiConn *APIConnection) *Batch {
+	return apiConn.NewBatch().
+		CreateRRSet("example.com.", &RRSet{OwnerName: "www", RRType: RRTypeA, TTL: 300, RData: []string{"192.0.2.1"}}).
+		JSONPatchRRSet("example.com.", RRTypeA, "api", jsonpatch.Patch{}.Add("/rdata/-", "192.0.2.2")).
+		DeleteZone("old.com.")
+}
+
+func TestBatchSend(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/batch", r.URL.Path)
+		var operations []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&operations))
+		if assert.Len(t, operations, 3) {
+			assert.Equal(t, "POST", operations[0]["method"])
+			assert.Equal(t, "/zones/example.com./rrsets/A/www.example.com.", operations[0]["uri"])
+			assert.Equal(t, "PATCH", operations[1]["method"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"op": "add", "path": "/rdata/-", "value": "192.0.2.2"}}, operations[1]["body"])
+			assert.Equal(t, "/zones/old.com.", operations[2]["uri"])
+			assert.NotContains(t, operations[2], "body")
+		}
+		w.Write([]byte(batchResponseJSON))
+	})
+	defer server.Close()
+
+	results, err := newTestBatch(apiConn).Send(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, results, 3) {
+		return
+	}
+
+	assert.Equal(t, 201, results[0].StatusCode)
+	assert.NoError(t, results[0].Err)
+	message := struct{ Message string }{}
+	assert.NoError(t, results[0].Decode(&message))
+	assert.Equal(t, "Successful", message.Message)
+
+	assert.Equal(t, "/zones/example.com./rrsets/A/api.example.com.", results[1].Operation.URI)
+	assert.Equal(t, 400, results[1].StatusCode)
+	assert.True(t, errors.Is(results[1].Err, apierror.AlreadyExists))
+	apiErr := &apierror.Error{}
+	if assert.True(t, errors.As(results[1].Err, &apiErr)) {
+		assert.Equal(t, "PATCH", apiErr.Method)
+		assert.Equal(t, server.URL+"/zones/example.com./rrsets/A/api.example.com.", apiErr.URL)
+	}
+
+	assert.Equal(t, 204, results[2].StatusCode)
+	assert.NoError(t, results[2].Err)
+
+	assert.EqualError(t, BatchErr(results), "batch operation 1 (PATCH /zones/example.com./rrsets/A/api.example.com.): 2111: Resource Record already exists.")
+}
+
+func TestBatchSendAsync(t *testing.T) {
+	var requests []string
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/batch":
+			w.Header().Set("X-Task-Id", "batch-1")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"message":"Pending"}`))
+		case "/tasks/batch-1":
+			w.Write([]byte(`{"taskId":"batch-1","code":"COMPLETE","resultUri":"/tasks/batch-1/result"}`))
+		case "/tasks/batch-1/result":
+			w.Write([]byte(batchResponseJSON))
+		}
+	})
+	defer server.Close()
+
+	results, err := newTestBatch(apiConn).SendAsync(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []string{"POST /batch?async=true", "GET /tasks/batch-1", "GET /tasks/batch-1/result"}, requests)
+}
+
+func TestBatchInvalidOperation(t *testing.T) {
+	apiConn := NewAPIConnection(&APIOptions{BaseURL: "http://127.0.0.1:0"})
+
+	_, err := apiConn.NewBatch().
+		DeleteZone("old.com.").
+		CreateRRSet("example.com.", &RRSet{OwnerName: "www", RRType: RRTypeA, RData: []string{"not an address"}}).
+		Send(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "batch operation 1")
+
+	_, err = apiConn.NewBatch().Send(context.Background())
+	assert.EqualError(t, err, "batch has no operations")
+}
+
+func TestBatchResultCountMismatch(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"code":200}]`))
+	})
+	defer server.Close()
+
+	_, err := newTestBatch(apiConn).Send(context.Background())
+	assert.EqualError(t, err, "batch of 3 operations returned 1 results")
+}
@@ -0,0 +1,259 @@
+package ultradns
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CassetteMode selects whether a Cassette replays or records interactions.
+type CassetteMode int
+
+// Modes of a Cassette.
+const (
+	// CassetteReplay answers requests from the recorded interactions, without any network access.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests to the API and records them, to be written with Save.
+	CassetteRecord
+)
+
+// ErrNoInteraction is returned by a replaying Cassette for a request that matches none of the remaining
+// interactions.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// RecordedRequest is a request of an Interaction. The body has its secrets replaced, see Cassette.
+type RecordedRequest struct {
+	Method string `json:"method" yaml:"method"`
+	Path   string `json:"path" yaml:"path"`
+	Query  string `json:"query,omitempty" yaml:"query,omitempty"`
+	Body   string `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an Interaction. The body has its secrets replaced, see Cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode" yaml:"statusCode"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Interaction is a request and the response it got.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request" yaml:"request"`
+	Response RecordedResponse `json:"response" yaml:"response"`
+}
+
+// cassetteFile is the content of a cassette file.
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Cassette records the requests of a connection to a fixture file and replays them, e.g. to write regression tests
+// from real API traffic that run without network access. It is an http.RoundTripper, used as the Transport of a
+// connection:
+//
+//	cassette, err := ultradns.NewCassette("testdata/zones.yaml", ultradns.CassetteReplay)
+//	apiConn := ultradns.NewAPIConnection(&ultradns.APIOptions{Username: "user", Password: "secret", Transport: cassette})
+//
+// The file is YAML if its name ends in .yaml or .yml, and JSON otherwise.
+//
+// Passwords and tokens in request and response bodies are replaced before they are recorded, as are the members and
+// form fields the logging redacts. Request headers are not recorded. Requests are matched on their method, path,
+// query and body, comparing the bodies after the same replacement, so a replayed connection may use any password.
+// Every interaction is replayed once, in the order recorded, so repeated requests such as polling a task get the
+// responses they got when recording.
+type Cassette struct {
+	// Path is the file the interactions are loaded from and saved to.
+	Path string
+
+	// Mode is whether the cassette replays or records.
+	Mode CassetteMode
+
+	// Transport sends the requests when recording. Default is http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette returns a cassette for the file at path. In CassetteReplay mode the file is loaded and must exist; in
+// CassetteRecord mode recording starts empty and Save overwrites the file.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	cassette := &Cassette{Path: path, Mode: mode}
+	if mode == CassetteRecord {
+		return cassette, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := cassetteFile{}
+	if cassette.isYAML() {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	cassette.interactions = file.Interactions
+	cassette.used = make([]bool, len(file.Interactions))
+	return cassette, nil
+}
+
+// Interactions returns the interactions loaded or recorded so far.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Remaining returns the number of interactions that have not been replayed yet, e.g. to check that a test sent all
+// the requests it was recorded with.
+func (c *Cassette) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	remaining := 0
+	for _, used := range c.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// Save writes the recorded interactions to the file, creating its directory if needed.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	file := cassetteFile{Interactions: append([]Interaction(nil), c.interactions...)}
+	c.mu.Unlock()
+
+	var data []byte
+	var err error
+	if c.isYAML() {
+		data, err = yaml.Marshal(file)
+	} else {
+		data, err = json.MarshalIndent(file, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.Path, data, 0644)
+}
+
+// RoundTrip replays or records the request, depending on the Mode.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := recordRequest(req, body)
+
+	if c.Mode == CassetteRecord {
+		return c.record(req, body, recorded)
+	}
+	return c.replay(req, recorded)
+}
+
+// record sends the request and appends it to the interactions along with its response.
+func (c *Cassette) record(req *http.Request, body []byte, recorded RecordedRequest) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	// A RoundTripper must not modify the request it was given.
+	outgoing := req.Clone(req.Context())
+	outgoing.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Date")
+	// The body may change length when secrets are replaced.
+	header.Del("Content-Length")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       string(scrubBody(respBody)),
+		},
+	})
+	c.used = append(c.used, true)
+	return resp, nil
+}
+
+// replay answers the request with the first unused interaction matching it.
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Request != recorded {
+			continue
+		}
+		c.used[i] = true
+		response := interaction.Response
+		header := response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(response.Body)),
+			ContentLength: int64(len(response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: %s %s: %w", c.Path, req.Method, req.URL, ErrNoInteraction)
+}
+
+// isYAML returns true if the file is YAML rather than JSON.
+func (c *Cassette) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(c.Path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// recordRequest returns the request as recorded and matched: the query is sorted and secrets are replaced in the
+// body.
+func recordRequest(req *http.Request, body []byte) RecordedRequest {
+	return RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query().Encode(),
+		Body:   string(scrubBody(body)),
+	}
+}
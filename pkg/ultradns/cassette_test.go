@@ -0,0 +1,102 @@
+package ultradns
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cassetteServer issues tokens for the valid credentials and echoes the query and body of other requests.
+func cassetteServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/authorization/token" {
+			w.Write([]byte(`{"accessToken":"` + validAccessToken + `","refreshToken":"` + validRefreshToken + `","expiresIn":"3600"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+validAccessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Task-Id", "task-"+r.URL.Query().Get("n"))
+		w.Write([]byte(`{"query":"` + r.URL.RawQuery + `","body":` + string(body) + `}`))
+	}))
+}
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"fixture.json", "fixture.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "testdata", name)
+			server := cassetteServer()
+
+			recorder, err := NewCassette(path, CassetteRecord)
+			assert.NoError(t, err)
+			apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL, Transport: recorder})
+			recorded := map[string]interface{}{}
+			assert.NoError(t, apiConn.PostJSON("/echo?n=1&b=2", map[string]string{"name": "www", "password": "hunter2"}, &recorded))
+			assert.NoError(t, recorder.Save())
+			server.Close()
+
+			data, err := ioutil.ReadFile(path)
+			assert.NoError(t, err)
+			for _, secret := range []string{validPassword, validAccessToken, validRefreshToken, "hunter2"} {
+				assert.NotContains(t, string(data), secret)
+			}
+
+			// Replay against the closed server, with another password and the query in another order.
+			player, err := NewCassette(path, CassetteReplay)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, player.Remaining())
+			apiConn = NewAPIConnection(&APIOptions{Username: validUsername, Password: "other", BaseURL: server.URL, Transport: player})
+			req, err := apiConn.NewRequest("POST", "/echo?b=2&n=1", nil)
+			assert.NoError(t, err)
+			body, err := encodeJSON(map[string]string{"password": "other", "name": "www"})
+			assert.NoError(t, err)
+			req.Body = ioutil.NopCloser(body)
+			resp, err := apiConn.Do(req)
+			assert.NoError(t, err)
+			assert.Equal(t, "task-1", resp.Header.Get("X-Task-Id"))
+			replayed := map[string]interface{}{}
+			assert.NoError(t, apiConn.decodeJSON(resp, &replayed))
+			assert.Equal(t, recorded["query"], replayed["query"])
+			assert.Equal(t, map[string]interface{}{"name": "www", "password": redacted}, replayed["body"])
+			assert.Equal(t, 0, player.Remaining())
+
+			// Every interaction is replayed only once.
+			err = apiConn.PostJSON("/echo?n=1&b=2", map[string]string{"name": "www"}, nil)
+			assert.True(t, errors.Is(err, ErrNoInteraction))
+		})
+	}
+}
+
+func TestCassetteDoesNotMatchDifferentRequests(t *testing.T) {
+	cassette := &Cassette{
+		Path: "fixture.json",
+		interactions: []Interaction{{
+			Request:  RecordedRequest{Method: "GET", Path: "/zones", Query: "limit=10"},
+			Response: RecordedResponse{StatusCode: 200, Body: `{}`},
+		}},
+		used: []bool{false},
+	}
+	for _, url := range []string{"/zones?limit=20", "/zones/example.com.", "/zones"} {
+		req := httptest.NewRequest("GET", url, nil)
+		_, err := cassette.RoundTrip(req)
+		assert.True(t, errors.Is(err, ErrNoInteraction), url)
+	}
+	_, err := cassette.RoundTrip(httptest.NewRequest("DELETE", "/zones?limit=10", nil))
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+
+	resp, err := cassette.RoundTrip(httptest.NewRequest("GET", "/zones?limit=10", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,141 @@
+package ultradns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DecodeError is returned when a successful response could not be decoded, e.g. because it is not the expected JSON
+// or, with StrictJSON, has unknown members.
+type DecodeError struct {
+	// Method and URL of the request.
+	Method string
+	URL    string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Err is the error of the JSON decoder.
+	Err error
+}
+
+// Error is the interface for the error type.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding response to %s %s: %s", e.Method, e.URL, e.Err)
+}
+
+// Unwrap returns the error of the JSON decoder.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// GetJSON executes a GET request at the given url and decodes the JSON response into out.
+func (apiConn *APIConnection) GetJSON(url string, out interface{}) error {
+	return apiConn.GetJSONContext(context.Background(), url, out)
+}
+
+// GetJSONContext is like GetJSON, but bound to the given context.
+func (apiConn *APIConnection) GetJSONContext(ctx context.Context, url string, out interface{}) error {
+	return apiConn.RequestJSON(ctx, "GET", url, nil, out)
+}
+
+// PostJSON marshals in to JSON, POSTs it to the given url and decodes the JSON response into out.
+func (apiConn *APIConnection) PostJSON(url string, in interface{}, out interface{}) error {
+	return apiConn.PostJSONContext(context.Background(), url, in, out)
+}
+
+// PostJSONContext is like PostJSON, but bound to the given context.
+func (apiConn *APIConnection) PostJSONContext(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return apiConn.RequestJSON(ctx, "POST", url, in, out)
+}
+
+// PutJSON marshals in to JSON, PUTs it to the given url and decodes the JSON response into out.
+func (apiConn *APIConnection) PutJSON(url string, in interface{}, out interface{}) error {
+	return apiConn.PutJSONContext(context.Background(), url, in, out)
+}
+
+// PutJSONContext is like PutJSON, but bound to the given context.
+func (apiConn *APIConnection) PutJSONContext(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return apiConn.RequestJSON(ctx, "PUT", url, in, out)
+}
+
+// PatchJSON marshals in to JSON, PATCHes the given url with it and decodes the JSON response into out.
+func (apiConn *APIConnection) PatchJSON(url string, in interface{}, out interface{}) error {
+	return apiConn.PatchJSONContext(context.Background(), url, in, out)
+}
+
+// PatchJSONContext is like PatchJSON, but bound to the given context.
+func (apiConn *APIConnection) PatchJSONContext(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return apiConn.RequestJSON(ctx, "PATCH", url, in, out)
+}
+
+// DeleteJSON executes a DELETE request at the given url and decodes the JSON response into out.
+func (apiConn *APIConnection) DeleteJSON(url string, out interface{}) error {
+	return apiConn.DeleteJSONContext(context.Background(), url, out)
+}
+
+// DeleteJSONContext is like DeleteJSON, but bound to the given context.
+func (apiConn *APIConnection) DeleteJSONContext(ctx context.Context, url string, out interface{}) error {
+	return apiConn.RequestJSON(ctx, "DELETE", url, nil, out)
+}
+
+// RequestJSON sends a request with in marshaled to JSON as its body and decodes the JSON response into out. A nil in
+// sends no body, and a nil out discards the response. The response body is always closed.
+//
+// API errors are returned like for the verb methods, see package apierror. A response that cannot be decoded
+// returns a *DecodeError.
+func (apiConn *APIConnection) RequestJSON(ctx context.Context, method string, url string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		var err error
+		if body, err = encodeJSON(in); err != nil {
+			return err
+		}
+	}
+	req, err := apiConn.NewRequestContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	resp, err := apiConn.Do(req)
+	if err != nil {
+		return err
+	}
+	return apiConn.decodeJSON(resp, out)
+}
+
+// encodeJSON marshals v into a reader suitable for passing to the verb methods.
+func encodeJSON(v interface{}) (io.Reader, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+// decodeJSON reads the response body into v and closes it. A nil v discards the body, as does a 204 No Content
+// response, which leaves v as is.
+func (apiConn *APIConnection) decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	decoder := json.NewDecoder(resp.Body)
+	if apiConn.StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		decodeErr := &DecodeError{StatusCode: resp.StatusCode, Err: err}
+		if resp.Request != nil {
+			decodeErr.Method = resp.Request.Method
+			decodeErr.URL = resp.Request.URL.String()
+		}
+		return decodeErr
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package ultradns
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeCounter is a response body that counts how often it was closed.
+type closeCounter struct {
+	io.ReadCloser
+	closed *int32
+}
+
+func (c closeCounter) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return c.ReadCloser.Close()
+}
+
+// countingCloses returns a Middleware that counts the closes of response bodies.
+func countingCloses(closed *int32) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if resp != nil {
+				resp.Body = closeCounter{ReadCloser: resp.Body, closed: closed}
+			}
+			return resp, err
+		})
+	}
+}
+
+func TestGetJSONDecodesResponse(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	out := struct {
+		FooBar string `json:"fooBar"`
+	}{}
+	assert.NoError(t, apiConn.GetJSON("/foo", &out))
+	assert.Equal(t, "isFooBar", out.FooBar)
+}
+
+func TestPostJSONMarshalsBody(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	in := map[string]string{"probing": "enable"}
+	out := struct {
+		Yep bool `json:"yep"`
+	}{}
+	assert.NoError(t, apiConn.PostJSON("/post/endpoint", in, &out))
+	assert.True(t, out.Yep)
+}
+
+func TestRequestJSONSendsNoBodyForNilInput(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Empty(t, body)
+		assert.Empty(t, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	out := struct{ Untouched string }{Untouched: "yes"}
+	assert.NoError(t, apiConn.DeleteJSON("/zones/example.com.", &out))
+	assert.Equal(t, "yes", out.Untouched)
+}
+
+func TestStrictJSONRejectsUnknownMembers(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	out := struct {
+		Other string `json:"other"`
+	}{}
+	assert.NoError(t, apiConn.GetJSON("/foo", &out))
+
+	apiConn.StrictJSON = true
+	err := apiConn.GetJSON("/foo", &out)
+	decodeErr := &DecodeError{}
+	if assert.True(t, errors.As(err, &decodeErr)) {
+		assert.Equal(t, "GET", decodeErr.Method)
+		assert.Equal(t, server.URL+"/foo", decodeErr.URL)
+		assert.Equal(t, http.StatusOK, decodeErr.StatusCode)
+		assert.Contains(t, decodeErr.Error(), `unknown field "fooBar"`)
+	}
+}
+
+func TestRequestJSONReturnsAPIErrors(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errorCode":1801,"errorMessage":"Zone does not exist in the system."}`))
+	})
+	defer server.Close()
+
+	err := apiConn.GetJSON("/zones/example.com.", &Zone{})
+	assert.True(t, errors.Is(err, apierror.NotFound))
+}
+
+func TestRequestJSONAlwaysClosesBody(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"success", 200, `{"yep":true}`},
+		{"API error", 400, `{"errorCode":1801,"errorMessage":"Zone does not exist in the system."}`},
+		{"invalid JSON", 200, `{"yep":`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+			defer server.Close()
+			var closed int32
+			apiConn.Use(countingCloses(&closed))
+
+			out := struct {
+				Yep bool `json:"yep"`
+			}{}
+			apiConn.GetJSON("/anything", &out)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&closed))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package ultradns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logger receives structured log records: a message followed by alternating keys and values. *slog.Logger satisfies
+// it, as does StdLogger for the log package.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// StdLogger adapts a *log.Logger to Logger, writing records as "level msg key=value ...".
+type StdLogger struct {
+	Logger *log.Logger
+}
+
+// Debug writes a debug record.
+func (l StdLogger) Debug(msg string, args ...interface{}) {
+	l.write("DEBUG", msg, args)
+}
+
+// Warn writes a warning record.
+func (l StdLogger) Warn(msg string, args ...interface{}) {
+	l.write("WARN", msg, args)
+}
+
+// write formats a record. A trailing key without a value is written as is.
+func (l StdLogger) write(level string, msg string, args []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", level, msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%q", args[i], fmt.Sprint(args[i+1]))
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	if l.Logger == nil {
+		log.Print(b.String())
+		return
+	}
+	l.Logger.Print(b.String())
+}
+
+// redacted replaces secrets in logs.
+const redacted = "[REDACTED]"
+
+// maxLoggedBody is the number of bytes of a body that are logged.
+const maxLoggedBody = 4096
+
+// secretKeys are the JSON members and form fields whose values are redacted from logged bodies and cassettes.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"accesstoken":   true,
+	"access_token":  true,
+	"refreshtoken":  true,
+	"refresh_token": true,
+	"token":         true,
+}
+
+// logAttempt logs a single attempt of a request at debug level. Bodies are only logged in Debug mode.
+func (apiConn *APIConnection) logAttempt(req *http.Request, payload []byte, resp *http.Response, respBody []byte, latency time.Duration, err error) {
+	if apiConn.Logger == nil {
+		return
+	}
+	args := []interface{}{
+		"method", req.Method,
+		"url", req.URL.String(),
+		"latency", latency,
+	}
+	if resp != nil {
+		args = append(args, "status", resp.StatusCode)
+	}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	if apiConn.Debug {
+		args = append(args,
+			"request_headers", redactHeaders(req.Header),
+			"request_body", redactBody(payload),
+		)
+		if resp != nil {
+			args = append(args, "response_body", redactBody(respBody))
+		}
+	}
+	apiConn.Logger.Debug("ultradns request", args...)
+}
+
+// logRetry logs that a request is retried after the delay.
+func (apiConn *APIConnection) logRetry(reason string, req *http.Request, delay time.Duration, err error) {
+	if apiConn.Logger == nil {
+		return
+	}
+	args := []interface{}{"method", req.Method, "url", req.URL.String(), "delay", delay}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	apiConn.Logger.Warn("ultradns request "+reason, args...)
+}
+
+// readBody reads the response body for logging and replaces it with a copy, so that it can still be read.
+func readBody(resp *http.Response) []byte {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return []byte(fmt.Sprintf("<error reading body: %s>", err))
+	}
+	return body
+}
+
+// redactHeaders formats the headers with the credentials redacted.
+func redactHeaders(header http.Header) string {
+	redactedHeader := http.Header{}
+	for key, values := range header {
+		if key == "Authorization" {
+			values = []string{redacted}
+		}
+		redactedHeader[key] = values
+	}
+	return fmt.Sprint(redactedHeader)
+}
+
+// redactBody formats a JSON or form encoded body for logging, with secrets redacted. Other bodies are logged as is.
+func redactBody(body []byte) string {
+	body = scrubBody(body)
+	if len(body) > maxLoggedBody {
+		return string(body[:maxLoggedBody]) + "..."
+	}
+	return string(body)
+}
+
+// scrubBody returns a JSON or form encoded body with the values of secrets replaced. JSON is re-encoded with sorted
+// members and form fields are sorted, so equivalent bodies scrub to the same bytes. Other bodies are returned as is.
+func scrubBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Numbers are kept as they are, instead of being rounded to float64.
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err == nil && !decoder.More() {
+		var redactedBody bytes.Buffer
+		encoder := json.NewEncoder(&redactedBody)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(redactJSON(doc)); err == nil {
+			return bytes.TrimSuffix(redactedBody.Bytes(), []byte("\n"))
+		}
+	} else if form, err := url.ParseQuery(string(body)); err == nil && strings.Contains(string(body), "=") {
+		for key := range form {
+			if secretKeys[strings.ToLower(key)] {
+				form.Set(key, redacted)
+			}
+		}
+		return []byte(form.Encode())
+	}
+	return body
+}
+
+// redactJSON replaces the values of secret members anywhere in a decoded JSON document.
+func redactJSON(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			if secretKeys[strings.ToLower(key)] {
+				n[key] = redacted
+			} else {
+				n[key] = redactJSON(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range n {
+			n[i] = redactJSON(value)
+		}
+	}
+	return node
+}
@@ -0,0 +1,151 @@
+package ultradns
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logRecord struct {
+	level string
+	msg   string
+	attrs map[string]interface{}
+}
+
+// recordingLogger keeps the records it receives.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) {
+	l.record("DEBUG", msg, args)
+}
+
+func (l *recordingLogger) Warn(msg string, args ...interface{}) {
+	l.record("WARN", msg, args)
+}
+
+func (l *recordingLogger) record(level string, msg string, args []interface{}) {
+	attrs := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[args[i].(string)] = args[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level, msg, attrs})
+}
+
+func TestLoggerRecordsRequests(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+	logger := &recordingLogger{}
+	apiConn.Logger = logger
+
+	resp, err := apiConn.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	if assert.Len(t, logger.records, 1) {
+		record := logger.records[0]
+		assert.Equal(t, "DEBUG", record.level)
+		assert.Equal(t, "GET", record.attrs["method"])
+		assert.Equal(t, server.URL+"/foo", record.attrs["url"])
+		assert.Equal(t, 200, record.attrs["status"])
+		assert.IsType(t, time.Duration(0), record.attrs["latency"])
+		// Bodies are only logged in debug mode.
+		assert.NotContains(t, record.attrs, "response_body")
+	}
+}
+
+func TestDebugLogsRedactedBodies(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"errorCode":55001,"errorMessage":"bad","accessToken":"leaked"}`))
+	})
+	defer server.Close()
+	logger := &recordingLogger{}
+	apiConn.Logger = logger
+	apiConn.Debug = true
+
+	_, err := apiConn.Post("/users", strings.NewReader(`{"name":"bob","password":"hunter2","nested":[{"refresh_token":"secret"}]}`))
+	// The error is still parsed from the body that was logged.
+	assert.EqualError(t, err, "55001: bad")
+
+	if assert.Len(t, logger.records, 1) {
+		attrs := logger.records[0].attrs
+		assert.Equal(t, 400, attrs["status"])
+		assert.Equal(t, `{"name":"bob","nested":[{"refresh_token":"[REDACTED]"}],"password":"[REDACTED]"}`, attrs["request_body"])
+		assert.Equal(t, `{"accessToken":"[REDACTED]","errorCode":55001,"errorMessage":"bad"}`, attrs["response_body"])
+		assert.Contains(t, attrs["request_headers"], "Authorization:[[REDACTED]]")
+		assert.NotContains(t, fmt.Sprint(attrs), validAccessToken)
+	}
+}
+
+func TestDebugLogsTokenRequests(t *testing.T) {
+	server := cassetteServer()
+	defer server.Close()
+	logger := &recordingLogger{}
+	apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL, Logger: logger, Debug: true})
+
+	assert.NoError(t, apiConn.GetJSON("/foo", nil))
+
+	if assert.Len(t, logger.records, 2) {
+		attrs := logger.records[0].attrs
+		assert.Equal(t, "POST", attrs["method"])
+		assert.Equal(t, server.URL+"/authorization/token", attrs["url"])
+		assert.Equal(t, 200, attrs["status"])
+		assert.Contains(t, attrs["request_body"], "password=%5BREDACTED%5D")
+		assert.Contains(t, attrs["response_body"], `"accessToken":"[REDACTED]"`)
+		assert.Equal(t, server.URL+"/foo", logger.records[1].attrs["url"])
+	}
+	for _, secret := range []string{validPassword, validAccessToken, validRefreshToken} {
+		assert.NotContains(t, fmt.Sprint(logger.records), secret)
+	}
+}
+
+func TestLoggerRecordsRetries(t *testing.T) {
+	calls := 0
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"errorCode":99999,"errorMessage":"try again"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer server.Close()
+	logger := &recordingLogger{}
+	apiConn.Logger = logger
+	apiConn.Retry = &RetryPolicy{BaseBackoff: time.Millisecond}
+
+	resp, err := apiConn.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	if assert.Len(t, logger.records, 3) {
+		assert.Equal(t, "WARN", logger.records[1].level)
+		assert.Equal(t, "ultradns request failed", logger.records[1].msg)
+		assert.EqualError(t, logger.records[1].attrs["error"].(error), "99999: try again")
+	}
+}
+
+func TestRedactBodyForm(t *testing.T) {
+	assert.Equal(t, "grant_type=password&password=%5BREDACTED%5D&username=bob", redactBody([]byte("grant_type=password&username=bob&password=hunter2")))
+	assert.Equal(t, "plain text", redactBody([]byte("plain text")))
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := StdLogger{Logger: log.New(&buf, "", 0)}
+	logger.Warn("ultradns request throttled", "method", "GET", "delay", time.Second)
+	assert.Equal(t, "WARN ultradns request throttled method=\"GET\" delay=\"1s\"\n", buf.String())
+}
@@ -0,0 +1,53 @@
+package ultradns
+
+import "net/http"
+
+// Middleware wraps the transport of an APIConnection, e.g. to add headers, record metrics or inject faults in
+// tests. It sees every attempt of every request, including the requests to the token endpoint
+// (/authorization/token), with the Authorization header already set.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, e.g. to write a Middleware:
+//
+//	func audit(next http.RoundTripper) http.RoundTripper {
+//		return ultradns.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//			log.Printf("%s %s", req.Method, req.URL.Path)
+//			return next.RoundTrip(req)
+//		})
+//	}
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls the function.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chain wraps the transport in the middlewares. The first middleware is the outermost one, so it sees requests first
+// and responses last.
+func chain(transport http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return transport
+}
+
+// Use wraps the connection's current transport in further middlewares, so they run outside the ones added earlier,
+// including those from APIOptions.Middleware. Not safe to call while requests are in flight.
+func (apiConn *APIConnection) Use(middlewares ...Middleware) {
+	apiConn.Client.Transport = chain(apiConn.Client.Transport, middlewares)
+}
+
+// SetHeader returns a Middleware that sets a header on every request, e.g. a User-Agent.
+func SetHeader(key string, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// A RoundTripper must not modify the request it was given.
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ultradns
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tracing returns a Middleware that appends name and the request path to calls.
+func tracing(name string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+" "+req.URL.Path)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestMiddlewareWrapsTokenAndAPIRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ultradns-go-test", r.Header.Get("User-Agent"))
+		if r.URL.Path == "/authorization/token" {
+			w.Write([]byte(`{"accessToken":"` + validAccessToken + `","refreshToken":"` + validRefreshToken + `","expiresIn":"3600"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var calls []string
+	apiConn := NewAPIConnection(&APIOptions{
+		Username: validUsername,
+		Password: validPassword,
+		BaseURL:  server.URL,
+		Middleware: []Middleware{
+			tracing("outer", &calls),
+			SetHeader("User-Agent", "ultradns-go-test"),
+			tracing("inner", &calls),
+		},
+	})
+
+	resp, err := apiConn.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{
+		"outer /authorization/token", "inner /authorization/token",
+		"outer /foo", "inner /foo",
+	}, calls)
+}
+
+func TestUseAddsOuterMiddleware(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	var calls []string
+	apiConn.Use(tracing("first", &calls))
+	apiConn.Use(tracing("second", &calls))
+
+	resp, err := apiConn.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{"second /foo", "first /foo"}, calls)
+}
+
+func TestMiddlewareInjectsFaults(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	injected := errors.New("injected fault")
+	apiConn.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, injected
+		})
+	})
+
+	_, err := apiConn.Get("/foo")
+	assert.True(t, errors.Is(err, injected), "expected the injected fault, got %v", err)
+}
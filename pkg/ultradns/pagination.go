@@ -0,0 +1,215 @@
+package ultradns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// QueryInfo echoes back the query parameters of a list request.
+type QueryInfo struct {
+	Q       string `json:"q,omitempty"`
+	Sort    string `json:"sort,omitempty"`
+	Reverse bool   `json:"reverse"`
+	Limit   int    `json:"limit"`
+}
+
+// ResultInfo describes which slice of the full result set a list response contains.
+type ResultInfo struct {
+	TotalCount    int `json:"totalCount"`
+	Offset        int `json:"offset"`
+	ReturnedCount int `json:"returnedCount"`
+}
+
+// ListOptions are the query parameters shared by the UltraDNS list endpoints. Zero values are left to the API defaults.
+type ListOptions struct {
+	// Q is the UltraDNS query string, e.g. "name:example zone_type:PRIMARY"
+	Q       string
+	Sort    string
+	Reverse bool
+	Offset  int
+	Limit   int
+}
+
+// values converts the options into URL query parameters.
+func (opts *ListOptions) values() url.Values {
+	values := url.Values{}
+	if opts == nil {
+		return values
+	}
+	if opts.Q != "" {
+		values.Set("q", opts.Q)
+	}
+	if opts.Sort != "" {
+		values.Set("sort", opts.Sort)
+	}
+	if opts.Reverse {
+		values.Set("reverse", "true")
+	}
+	if opts.Offset > 0 {
+		values.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		values.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return values
+}
+
+// withQuery appends the encoded query to path if there is one.
+func withQuery(path string, values url.Values) string {
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
+
+// CursorInfo holds the cursors returned by cursor based listings such as /v3/zones. An empty Next marks the last page.
+type CursorInfo struct {
+	First    string `json:"first,omitempty"`
+	Previous string `json:"previous,omitempty"`
+	Next     string `json:"next,omitempty"`
+	Last     string `json:"last,omitempty"`
+}
+
+// Page is a single page of a list endpoint.
+type Page struct {
+	QueryInfo  QueryInfo   `json:"queryInfo"`
+	ResultInfo ResultInfo  `json:"resultInfo"`
+	CursorInfo *CursorInfo `json:"cursorInfo,omitempty"`
+
+	// Body is the raw JSON of the whole page. Use Decode to unmarshal the list items.
+	Body json.RawMessage `json:"-"`
+
+	// strict is the StrictJSON setting of the connection the page was fetched with.
+	strict bool
+}
+
+// Decode unmarshals the raw page into v, typically a struct holding the endpoint specific list, e.g. ZoneList. If the
+// page was fetched with StrictJSON, members of the page that v lacks are an error, like for GetJSON.
+func (page *Page) Decode(v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(page.Body))
+	if page.strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// Paginator walks all pages of a list endpoint. It is used like bufio.Scanner:
+//
+//	pages := apiConn.Paginate("/zones", nil)
+//	for pages.Next(ctx) {
+//		list := ZoneList{}
+//		if err := pages.Page().Decode(&list); err != nil { ... }
+//	}
+//	if err := pages.Err(); err != nil { ... }
+type Paginator struct {
+	apiConn *APIConnection
+	path    string
+	query   url.Values
+	cursor  bool
+
+	offset     int
+	nextCursor string
+	done       bool
+	page       *Page
+	err        error
+}
+
+// Paginate returns a Paginator that follows offset based pagination, as used by e.g. /zones and
+// /zones/{zone}/rrsets. Pages are requested until ResultInfo.TotalCount is exhausted.
+// The query may be nil. A "limit" in the query sets the page size, an "offset" the starting point.
+func (apiConn *APIConnection) Paginate(path string, query url.Values) *Paginator {
+	paginator := &Paginator{apiConn: apiConn, path: path, query: copyValues(query)}
+	if offset, err := strconv.Atoi(paginator.query.Get("offset")); err == nil {
+		paginator.offset = offset
+	}
+	return paginator
+}
+
+// PaginateCursor returns a Paginator that follows cursor based pagination, as used by /v3/zones. Pages are requested
+// until the response no longer has a next cursor.
+// The query may be nil. A "cursor" in the query sets the starting point.
+func (apiConn *APIConnection) PaginateCursor(path string, query url.Values) *Paginator {
+	paginator := &Paginator{apiConn: apiConn, path: path, query: copyValues(query), cursor: true}
+	paginator.nextCursor = paginator.query.Get("cursor")
+	return paginator
+}
+
+// Next fetches the next page. It returns false when there are no more pages or an error occurred, see Err.
+func (p *Paginator) Next(ctx context.Context) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	query := copyValues(p.query)
+	if p.cursor {
+		query.Del("cursor")
+		if p.nextCursor != "" {
+			query.Set("cursor", p.nextCursor)
+		}
+	} else {
+		query.Set("offset", strconv.Itoa(p.offset))
+	}
+
+	resp, err := p.apiConn.GetContext(ctx, withQuery(p.path, query))
+	if err != nil {
+		p.err = err
+		return false
+	}
+	page := &Page{strict: p.apiConn.StrictJSON}
+	if err = p.apiConn.decodeJSON(resp, &page.Body); err != nil {
+		p.err = err
+		return false
+	}
+	if err = json.Unmarshal(page.Body, page); err != nil {
+		p.err = err
+		return false
+	}
+	p.page = page
+
+	if p.cursor {
+		// Guard against an API that keeps handing back the same cursor.
+		if page.CursorInfo == nil || page.CursorInfo.Next == "" || page.CursorInfo.Next == p.nextCursor {
+			p.done = true
+		} else {
+			p.nextCursor = page.CursorInfo.Next
+		}
+	} else {
+		p.offset = page.ResultInfo.Offset + page.ResultInfo.ReturnedCount
+		if page.ResultInfo.ReturnedCount == 0 || p.offset >= page.ResultInfo.TotalCount {
+			p.done = true
+		}
+	}
+	return true
+}
+
+// Page returns the page fetched by the last successful call to Next.
+func (p *Paginator) Page() *Page {
+	return p.page
+}
+
+// Err returns the error that stopped the pagination, if any.
+func (p *Paginator) Err() error {
+	return p.err
+}
+
+// Walk calls fn for every remaining page, stopping at the first error returned by either the API or fn.
+func (p *Paginator) Walk(ctx context.Context, fn func(page *Page) error) error {
+	for p.Next(ctx) {
+		if err := fn(p.Page()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// copyValues returns a copy of values, so that paginators never modify the caller's query. Nil yields an empty set.
+func copyValues(values url.Values) url.Values {
+	copied := url.Values{}
+	for key, value := range values {
+		copied[key] = append([]string(nil), value...)
+	}
+	return copied
+}
@@ -0,0 +1,134 @@
+package ultradns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zoneNames returns n zone names, zone0.com. through zone<n-1>.com.
+func zoneNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("zone%d.com.", i)
+	}
+	return names
+}
+
+// zonesPageJSON renders the given zone names as a zone list page.
+func zonesPageJSON(names []string, offset int, total int, cursorInfo string) string {
+	zones := make([]string, len(names))
+	for i, name := range names {
+		zones[i] = `{"properties":{"name":"` + name + `","type":"PRIMARY"}}`
+	}
+	return `{"queryInfo":{"limit":2},"resultInfo":{"totalCount":` + strconv.Itoa(total) + `,"offset":` + strconv.Itoa(offset) +
+		`,"returnedCount":` + strconv.Itoa(len(names)) + `}` + cursorInfo + `,"zones":[` + strings.Join(zones, ",") + `]}`
+}
+
+func TestPaginateFollowsOffsets(t *testing.T) {
+	all := zoneNames(5)
+	requests := 0
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/zones", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := offset + 2
+		if end > len(all) {
+			end = len(all)
+		}
+		w.Write([]byte(zonesPageJSON(all[offset:end], offset, len(all), "")))
+	})
+	defer server.Close()
+
+	var names []string
+	pages := apiConn.Paginate("/zones", url.Values{"limit": {"2"}})
+	err := pages.Walk(context.Background(), func(page *Page) error {
+		list := ZoneList{}
+		assert.NoError(t, page.Decode(&list))
+		for _, zone := range list.Zones {
+			names = append(names, zone.Properties.Name)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, all, names)
+	assert.Equal(t, 3, requests)
+}
+
+func TestPaginateStopsOnEmptyPage(t *testing.T) {
+	requests := 0
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Claims more results than it ever returns.
+		w.Write([]byte(zonesPageJSON(nil, 0, 10, "")))
+	})
+	defer server.Close()
+
+	pages := apiConn.Paginate("/zones", nil)
+	assert.True(t, pages.Next(context.Background()))
+	assert.False(t, pages.Next(context.Background()))
+	assert.NoError(t, pages.Err())
+	assert.Equal(t, 1, requests)
+}
+
+func TestPaginateReportsErrors(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		w.Write([]byte(`{"errorCode":99999,"errorMessage":"boom"}`))
+	})
+	defer server.Close()
+
+	pages := apiConn.Paginate("/zones", nil)
+	assert.False(t, pages.Next(context.Background()))
+	assert.EqualError(t, pages.Err(), "99999: boom")
+}
+
+func TestZonesListAllFollowsCursors(t *testing.T) {
+	all := zoneNames(3)
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/zones", r.URL.Path)
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(zonesPageJSON(all[:2], 0, 3, `,"cursorInfo":{"next":"abc","last":"abc"}`)))
+		case "abc":
+			w.Write([]byte(zonesPageJSON(all[2:], 0, 3, `,"cursorInfo":{"previous":"xyz","first":"xyz"}`)))
+		default:
+			t.Errorf("unexpected cursor %s", r.URL.Query().Get("cursor"))
+		}
+	})
+	defer server.Close()
+
+	zones, err := apiConn.Zones().ListAll(context.Background(), &ListOptions{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, zones, 3)
+	assert.Equal(t, "zone2.com.", zones[2].Properties.Name)
+}
+
+func TestZonesListAllHonorsStrictJSON(t *testing.T) {
+	extra := `,"cursorInfo":{"first":"abc"},"unexpected":true`
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(zonesPageJSON(zoneNames(1), 0, 1, extra)))
+	})
+	defer server.Close()
+
+	_, err := apiConn.Zones().ListAll(context.Background(), nil)
+	assert.NoError(t, err)
+
+	apiConn.StrictJSON = true
+	_, err = apiConn.Zones().ListAll(context.Background(), nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unknown field "unexpected"`)
+	}
+
+	// The cursors of the page are known members of a ZoneList.
+	extra = `,"cursorInfo":{"first":"abc"}`
+	_, err = apiConn.Zones().ListAll(context.Background(), nil)
+	assert.NoError(t, err)
+}
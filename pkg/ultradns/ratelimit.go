@@ -0,0 +1,137 @@
+package ultradns
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit configures client side rate limiting, see APIOptions.RateLimit.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate. 0 does not limit the rate, but throttled responses still pause
+	// all requests sharing the limiter.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may be sent at once after a quiet period. Default is 1.
+	Burst int
+
+	// MaxThrottledRetries is how often a request rejected with 429 Too Many Requests is retried. Default is 3; set a
+	// negative value to disable retrying throttled requests.
+	MaxThrottledRetries int
+}
+
+// defaultMaxThrottledRetries is used for connections without a RateLimiter.
+const defaultMaxThrottledRetries = 3
+
+// maxRetryAfter is the longest Retry-After that is waited for. Longer ones are returned to the caller as errors.
+const maxRetryAfter = 5 * time.Minute
+
+// RateLimiter is a token bucket shared by all requests of an APIConnection. A throttled response pauses the whole
+// bucket until the server's Retry-After has passed, so concurrent requests back off together.
+// A nil *RateLimiter does not limit anything.
+type RateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	maxRetries  int
+}
+
+// NewRateLimiter returns a RateLimiter for the given limits, with a full bucket.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	if limit.MaxThrottledRetries == 0 {
+		limit.MaxThrottledRetries = defaultMaxThrottledRetries
+	}
+	return &RateLimiter{
+		rate:       limit.RequestsPerSecond,
+		burst:      float64(limit.Burst),
+		tokens:     float64(limit.Burst),
+		last:       time.Now(),
+		maxRetries: limit.MaxThrottledRetries,
+	}
+}
+
+// Wait blocks until a request may be sent, or until the context is done.
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+	for {
+		delay := limiter.reserve(time.Now())
+		if delay <= 0 {
+			return nil
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available and returns 0, or otherwise returns how long to wait before trying again.
+func (limiter *RateLimiter) reserve(now time.Time) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if now.Before(limiter.pausedUntil) {
+		return limiter.pausedUntil.Sub(now)
+	}
+	if limiter.rate <= 0 {
+		return 0
+	}
+
+	limiter.tokens = math.Min(limiter.burst, limiter.tokens+now.Sub(limiter.last).Seconds()*limiter.rate)
+	limiter.last = now
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return 0
+	}
+	return time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+// Pause holds back all requests for the given duration, e.g. after the API throttled a request.
+func (limiter *RateLimiter) Pause(delay time.Duration) {
+	if limiter == nil {
+		return
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(limiter.pausedUntil) {
+		limiter.pausedUntil = until
+	}
+}
+
+// maxThrottledRetries returns how often throttled requests are retried.
+func (limiter *RateLimiter) maxThrottledRetries() int {
+	if limiter == nil {
+		return defaultMaxThrottledRetries
+	}
+	return limiter.maxRetries
+}
+
+// throttleDelay returns how long to wait before retrying a throttled response, and false if the response was not
+// throttled or the server asked for an unreasonably long wait.
+func throttleDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	delay := time.Second
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(retryAfter); err == nil {
+			delay = date.Sub(now)
+		}
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, delay <= maxRetryAfter
+}
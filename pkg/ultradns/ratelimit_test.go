@@ -0,0 +1,144 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterPacesRequests(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 50, Burst: 2})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, limiter.Wait(ctx))
+	}
+	// The burst of 2 is free, the other 4 requests take 20ms each.
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 70*time.Millisecond, "finished too fast: %s", elapsed)
+	assert.True(t, elapsed < time.Second, "finished too slow: %s", elapsed)
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{})
+	limiter.Pause(50 * time.Millisecond)
+
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
+	limiter.Pause(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(limiter.Wait(ctx), context.DeadlineExceeded))
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var limiter *RateLimiter
+	assert.NoError(t, limiter.Wait(context.Background()))
+	limiter.Pause(time.Hour)
+	assert.Equal(t, defaultMaxThrottledRetries, limiter.maxThrottledRetries())
+}
+
+func TestThrottleDelay(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := &http.Response{StatusCode: 429, Header: http.Header{}}
+
+	delay, ok := throttleDelay(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	resp.Header.Set("Retry-After", "7")
+	delay, ok = throttleDelay(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, delay)
+
+	resp.Header.Set("Retry-After", now.Add(30*time.Second).Format(http.TimeFormat))
+	delay, ok = throttleDelay(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	resp.Header.Set("Retry-After", "86400")
+	_, ok = throttleDelay(resp, now)
+	assert.False(t, ok)
+
+	_, ok = throttleDelay(&http.Response{StatusCode: 503}, now)
+	assert.False(t, ok)
+}
+
+// throttlingServer answers the first throttles requests with 429 and a zero Retry-After.
+func throttlingServer(t *testing.T, throttles int, attempts *int) (func(), *APIConnection) {
+	var mu sync.Mutex
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		*attempts++
+		if *attempts <= throttles {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+			w.Write([]byte(`{"errorCode":429,"errorMessage":"Too many requests"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+	return server.Close, apiConn
+}
+
+func TestThrottledRequestsAreRetried(t *testing.T) {
+	attempts := 0
+	closeServer, apiConn := throttlingServer(t, 2, &attempts)
+	defer closeServer()
+
+	// Even POST is retried, as throttled requests were never processed.
+	resp, err := apiConn.Post("/foo", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 3, attempts)
+}
+
+func TestThrottledRetriesAreLimited(t *testing.T) {
+	attempts := 0
+	closeServer, apiConn := throttlingServer(t, 100, &attempts)
+	defer closeServer()
+	apiConn.Limiter = NewRateLimiter(RateLimit{MaxThrottledRetries: 1})
+
+	_, err := apiConn.Get("/foo")
+	assert.EqualError(t, err, "429: Too many requests")
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	apiConn.Limiter = NewRateLimiter(RateLimit{MaxThrottledRetries: -1})
+	_, err = apiConn.Get("/foo")
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestAPIOptionsRateLimit(t *testing.T) {
+	apiConn := NewAPIConnection(&APIOptions{RateLimit: &RateLimit{RequestsPerSecond: 10}})
+	assert.NotNil(t, apiConn.Limiter)
+	assert.Nil(t, NewAPIConnection(&APIOptions{}).Limiter)
+}
+
+func TestRateLimiterPacesTokenRequests(t *testing.T) {
+	server := cassetteServer()
+	defer server.Close()
+	apiConn := NewAPIConnection(&APIOptions{
+		Username:  validUsername,
+		Password:  validPassword,
+		BaseURL:   server.URL,
+		RateLimit: &RateLimit{RequestsPerSecond: 20, Burst: 1},
+	})
+
+	// The request takes the burst, so its token request waits 50ms for the bucket to refill.
+	start := time.Now()
+	assert.NoError(t, apiConn.GetJSON("/foo", nil))
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 40*time.Millisecond, "finished too fast: %s", elapsed)
+	assert.True(t, elapsed < time.Second, "finished too slow: %s", elapsed)
+}
@@ -0,0 +1,691 @@
+package ultradns
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// RData is a single typed record of a record set. String returns the record in the presentation format UltraDNS
+// uses in RRSet.RData, and ParseRData is its inverse.
+type RData interface {
+	RRType() RRType
+	String() string
+}
+
+// RDataError is returned when a record does not parse for its record type.
+type RDataError struct {
+	RRType RRType
+	RData  string
+	Err    error
+}
+
+// Error implements error.
+func (e *RDataError) Error() string {
+	return fmt.Sprintf("invalid %s rdata '%s': %s", e.RRType, e.RData, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *RDataError) Unwrap() error {
+	return e.Err
+}
+
+// ParseRData parses a record in presentation format into the typed record for rrtype. Record types without a typed
+// representation are returned as *GenericRecord, without validation.
+func ParseRData(rrtype RRType, s string) (RData, error) {
+	rdata, err := parseRData(rrtype, s)
+	if err != nil {
+		return nil, &RDataError{RRType: rrtype, RData: s, Err: err}
+	}
+	return rdata, nil
+}
+
+// parseRData dispatches to the parser for rrtype.
+func parseRData(rrtype RRType, s string) (RData, error) {
+	switch rrtype {
+	case RRTypeA:
+		return parseA(s)
+	case RRTypeAAAA:
+		return parseAAAA(s)
+	case RRTypeCNAME:
+		target, err := parseSingleName(s)
+		return &CNAMERecord{Target: target}, err
+	case RRTypeNS:
+		host, err := parseSingleName(s)
+		return &NSRecord{Host: host}, err
+	case RRTypePTR:
+		target, err := parseSingleName(s)
+		return &PTRRecord{Target: target}, err
+	case RRTypeMX:
+		return parseMX(s)
+	case RRTypeSRV:
+		return parseSRV(s)
+	case RRTypeTXT:
+		strs, err := parseTextStrings(s)
+		return &TXTRecord{Strings: strs}, err
+	case RRTypeSPF:
+		strs, err := parseTextStrings(s)
+		return &SPFRecord{Strings: strs}, err
+	case RRTypeCAA:
+		return parseCAA(s)
+	case RRTypeSOA:
+		return parseSOA(s)
+	case RRTypeHINFO:
+		return parseHINFO(s)
+	case RRTypeNAPTR:
+		return parseNAPTR(s)
+	case RRTypeSSHFP:
+		return parseSSHFP(s)
+	case RRTypeTLSA:
+		return parseTLSA(s)
+	case RRTypeDS:
+		return parseDS(s)
+	default:
+		return &GenericRecord{Type: rrtype, Data: s}, nil
+	}
+}
+
+// Records parses the record set's RData into typed records.
+func (rrset *RRSet) Records() ([]RData, error) {
+	records := make([]RData, len(rrset.RData))
+	for i, s := range rrset.RData {
+		record, err := ParseRData(rrset.RRType, s)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// SetRecords replaces the record set's RData with the given records, and sets RRType to theirs. All records must be
+// of the same type.
+func (rrset *RRSet) SetRecords(records ...RData) error {
+	rdata := make([]string, len(records))
+	for i, record := range records {
+		if record.RRType() != records[0].RRType() {
+			return fmt.Errorf("cannot mix %s and %s records in one record set", records[0].RRType(), record.RRType())
+		}
+		rdata[i] = record.String()
+	}
+	if len(records) > 0 {
+		rrset.RRType = records[0].RRType()
+	}
+	rrset.RData = rdata
+	return nil
+}
+
+// Validate checks that the record set has a type and that every record parses for it.
+func (rrset *RRSet) Validate() error {
+	if rrset.RRType == 0 {
+		return errors.New("record set has no record type")
+	}
+	_, err := rrset.Records()
+	return err
+}
+
+// GenericRecord holds a record of a type without a typed representation, e.g. APEXALIAS.
+type GenericRecord struct {
+	Type RRType
+	Data string
+}
+
+// RRType implements RData.
+func (r *GenericRecord) RRType() RRType { return r.Type }
+
+// String implements RData.
+func (r *GenericRecord) String() string { return r.Data }
+
+// ARecord is an IPv4 address record.
+type ARecord struct {
+	IP net.IP
+}
+
+// RRType implements RData.
+func (r *ARecord) RRType() RRType { return RRTypeA }
+
+// String implements RData.
+func (r *ARecord) String() string { return r.IP.String() }
+
+func parseA(s string) (*ARecord, error) {
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() == nil {
+		return nil, errors.New("not an IPv4 address")
+	}
+	return &ARecord{IP: ip.To4()}, nil
+}
+
+// AAAARecord is an IPv6 address record.
+type AAAARecord struct {
+	IP net.IP
+}
+
+// RRType implements RData.
+func (r *AAAARecord) RRType() RRType { return RRTypeAAAA }
+
+// String implements RData. IPv4-mapped addresses keep their IPv6 form, which net.IP.String would drop.
+func (r *AAAARecord) String() string {
+	if ip4 := r.IP.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+	return r.IP.String()
+}
+
+func parseAAAA(s string) (*AAAARecord, error) {
+	ip := net.ParseIP(s)
+	if ip == nil || !strings.Contains(s, ":") {
+		return nil, errors.New("not an IPv6 address")
+	}
+	return &AAAARecord{IP: ip}, nil
+}
+
+// CNAMERecord is a canonical name record.
+type CNAMERecord struct {
+	Target string
+}
+
+// RRType implements RData.
+func (r *CNAMERecord) RRType() RRType { return RRTypeCNAME }
+
+// String implements RData.
+func (r *CNAMERecord) String() string { return r.Target }
+
+// NSRecord is a name server record.
+type NSRecord struct {
+	Host string
+}
+
+// RRType implements RData.
+func (r *NSRecord) RRType() RRType { return RRTypeNS }
+
+// String implements RData.
+func (r *NSRecord) String() string { return r.Host }
+
+// PTRRecord is a pointer record.
+type PTRRecord struct {
+	Target string
+}
+
+// RRType implements RData.
+func (r *PTRRecord) RRType() RRType { return RRTypePTR }
+
+// String implements RData.
+func (r *PTRRecord) String() string { return r.Target }
+
+// MXRecord is a mail exchanger record, e.g. "10 mail.example.com."
+type MXRecord struct {
+	Preference uint16
+	Exchange   string
+}
+
+// RRType implements RData.
+func (r *MXRecord) RRType() RRType { return RRTypeMX }
+
+// String implements RData.
+func (r *MXRecord) String() string {
+	return fmt.Sprintf("%d %s", r.Preference, r.Exchange)
+}
+
+func parseMX(s string) (*MXRecord, error) {
+	fields, err := splitFields(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	r := &MXRecord{}
+	if r.Preference, err = parseUint16("preference", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Exchange, err = parseName(fields[1]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SRVRecord is a service locator record, e.g. "10 60 5060 sip.example.com."
+type SRVRecord struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// RRType implements RData.
+func (r *SRVRecord) RRType() RRType { return RRTypeSRV }
+
+// String implements RData.
+func (r *SRVRecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target)
+}
+
+func parseSRV(s string) (*SRVRecord, error) {
+	fields, err := splitFields(s, 4)
+	if err != nil {
+		return nil, err
+	}
+	r := &SRVRecord{}
+	if r.Priority, err = parseUint16("priority", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Weight, err = parseUint16("weight", fields[1]); err != nil {
+		return nil, err
+	}
+	if r.Port, err = parseUint16("port", fields[2]); err != nil {
+		return nil, err
+	}
+	if r.Target, err = parseName(fields[3]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TXTRecord is a text record made up of one or more character strings.
+// UltraDNS stores a single string unquoted; it is only quoted in String when there are several strings or it
+// contains quotes or backslashes.
+type TXTRecord struct {
+	Strings []string
+}
+
+// RRType implements RData.
+func (r *TXTRecord) RRType() RRType { return RRTypeTXT }
+
+// String implements RData.
+func (r *TXTRecord) String() string { return formatTextStrings(r.Strings) }
+
+// SPFRecord is the deprecated SPF record type. It has the same format as TXTRecord.
+type SPFRecord struct {
+	Strings []string
+}
+
+// RRType implements RData.
+func (r *SPFRecord) RRType() RRType { return RRTypeSPF }
+
+// String implements RData.
+func (r *SPFRecord) String() string { return formatTextStrings(r.Strings) }
+
+// parseTextStrings parses the character strings of a TXT or SPF record. Unquoted input is a single string, kept
+// verbatim including whitespace. It may not contain backslashes, which would be ambiguous: quote the string and escape
+// them instead.
+func parseTextStrings(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errors.New("empty text")
+	}
+	if !strings.Contains(s, `"`) {
+		if strings.Contains(s, `\`) {
+			return nil, errors.New("unquoted text must not contain backslashes")
+		}
+		return []string{s}, nil
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(tokens))
+	for i, token := range tokens {
+		strs[i] = token.value
+	}
+	return strs, nil
+}
+
+// formatTextStrings is the inverse of parseTextStrings.
+func formatTextStrings(strs []string) string {
+	if len(strs) == 1 && !strings.ContainsAny(strs[0], `"\`) {
+		return strs[0]
+	}
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = quote(s)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CAARecord is a certification authority authorization record, e.g. `0 issue "letsencrypt.org"`
+type CAARecord struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// RRType implements RData.
+func (r *CAARecord) RRType() RRType { return RRTypeCAA }
+
+// String implements RData.
+func (r *CAARecord) String() string {
+	return fmt.Sprintf("%d %s %s", r.Flags, r.Tag, quote(r.Value))
+}
+
+func parseCAA(s string) (*CAARecord, error) {
+	fields, err := splitFields(s, 3)
+	if err != nil {
+		return nil, err
+	}
+	r := &CAARecord{Tag: fields[1], Value: fields[2]}
+	if r.Flags, err = parseUint8("flags", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Tag == "" || strings.IndexFunc(r.Tag, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+	}) >= 0 {
+		return nil, fmt.Errorf("tag '%s' must be alphanumeric", r.Tag)
+	}
+	return r, nil
+}
+
+// SOARecord is a start of authority record.
+type SOARecord struct {
+	MName   string
+	RName   string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+}
+
+// RRType implements RData.
+func (r *SOARecord) RRType() RRType { return RRTypeSOA }
+
+// String implements RData.
+func (r *SOARecord) String() string {
+	return fmt.Sprintf("%s %s %d %d %d %d %d", r.MName, r.RName, r.Serial, r.Refresh, r.Retry, r.Expire, r.Minimum)
+}
+
+func parseSOA(s string) (*SOARecord, error) {
+	fields, err := splitFields(s, 7)
+	if err != nil {
+		return nil, err
+	}
+	r := &SOARecord{}
+	if r.MName, err = parseName(fields[0]); err != nil {
+		return nil, err
+	}
+	if r.RName, err = parseName(fields[1]); err != nil {
+		return nil, err
+	}
+	numbers := []*uint32{&r.Serial, &r.Refresh, &r.Retry, &r.Expire, &r.Minimum}
+	names := []string{"serial", "refresh", "retry", "expire", "minimum"}
+	for i, number := range numbers {
+		value, err := strconv.ParseUint(fields[i+2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s '%s' is not a 32 bit number", names[i], fields[i+2])
+		}
+		*number = uint32(value)
+	}
+	return r, nil
+}
+
+// HINFORecord is a host information record, e.g. `"x86_64" "Linux"`
+type HINFORecord struct {
+	CPU string
+	OS  string
+}
+
+// RRType implements RData.
+func (r *HINFORecord) RRType() RRType { return RRTypeHINFO }
+
+// String implements RData.
+func (r *HINFORecord) String() string {
+	return quote(r.CPU) + " " + quote(r.OS)
+}
+
+func parseHINFO(s string) (*HINFORecord, error) {
+	fields, err := splitFields(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &HINFORecord{CPU: fields[0], OS: fields[1]}, nil
+}
+
+// NAPTRRecord is a naming authority pointer record, e.g. `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`
+type NAPTRRecord struct {
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Services    string
+	Regexp      string
+	Replacement string
+}
+
+// RRType implements RData.
+func (r *NAPTRRecord) RRType() RRType { return RRTypeNAPTR }
+
+// String implements RData.
+func (r *NAPTRRecord) String() string {
+	return fmt.Sprintf("%d %d %s %s %s %s", r.Order, r.Preference, quote(r.Flags), quote(r.Services), quote(r.Regexp), r.Replacement)
+}
+
+func parseNAPTR(s string) (*NAPTRRecord, error) {
+	fields, err := splitFields(s, 6)
+	if err != nil {
+		return nil, err
+	}
+	r := &NAPTRRecord{Flags: fields[2], Services: fields[3], Regexp: fields[4]}
+	if r.Order, err = parseUint16("order", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Preference, err = parseUint16("preference", fields[1]); err != nil {
+		return nil, err
+	}
+	if r.Replacement, err = parseName(fields[5]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SSHFPRecord is an SSH public key fingerprint record, e.g. "1 2 <hex fingerprint>"
+type SSHFPRecord struct {
+	Algorithm   uint8
+	Type        uint8
+	Fingerprint string
+}
+
+// RRType implements RData.
+func (r *SSHFPRecord) RRType() RRType { return RRTypeSSHFP }
+
+// String implements RData.
+func (r *SSHFPRecord) String() string {
+	return fmt.Sprintf("%d %d %s", r.Algorithm, r.Type, r.Fingerprint)
+}
+
+func parseSSHFP(s string) (*SSHFPRecord, error) {
+	fields, err := splitFields(s, 3)
+	if err != nil {
+		return nil, err
+	}
+	r := &SSHFPRecord{}
+	if r.Algorithm, err = parseUint8("algorithm", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Type, err = parseUint8("fingerprint type", fields[1]); err != nil {
+		return nil, err
+	}
+	if r.Fingerprint, err = parseHex("fingerprint", fields[2]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSARecord is a DANE TLS association record, e.g. "3 1 1 <hex data>"
+type TLSARecord struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Certificate  string
+}
+
+// RRType implements RData.
+func (r *TLSARecord) RRType() RRType { return RRTypeTLSA }
+
+// String implements RData.
+func (r *TLSARecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Usage, r.Selector, r.MatchingType, r.Certificate)
+}
+
+func parseTLSA(s string) (*TLSARecord, error) {
+	fields, err := splitFields(s, 4)
+	if err != nil {
+		return nil, err
+	}
+	r := &TLSARecord{}
+	if r.Usage, err = parseUint8("usage", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Selector, err = parseUint8("selector", fields[1]); err != nil {
+		return nil, err
+	}
+	if r.MatchingType, err = parseUint8("matching type", fields[2]); err != nil {
+		return nil, err
+	}
+	if r.Certificate, err = parseHex("certificate data", fields[3]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DSRecord is a delegation signer record, e.g. "12345 13 2 <hex digest>"
+type DSRecord struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     string
+}
+
+// RRType implements RData.
+func (r *DSRecord) RRType() RRType { return RRTypeDS }
+
+// String implements RData.
+func (r *DSRecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.KeyTag, r.Algorithm, r.DigestType, r.Digest)
+}
+
+func parseDS(s string) (*DSRecord, error) {
+	fields, err := splitFields(s, 4)
+	if err != nil {
+		return nil, err
+	}
+	r := &DSRecord{}
+	if r.KeyTag, err = parseUint16("key tag", fields[0]); err != nil {
+		return nil, err
+	}
+	if r.Algorithm, err = parseUint8("algorithm", fields[1]); err != nil {
+		return nil, err
+	}
+	if r.DigestType, err = parseUint8("digest type", fields[2]); err != nil {
+		return nil, err
+	}
+	if r.Digest, err = parseHex("digest", fields[3]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// token is a single whitespace separated field of a record, with its quotes and escapes removed.
+type token struct {
+	value  string
+	quoted bool
+}
+
+// tokenize splits a record into whitespace separated fields. Double quoted fields may contain whitespace and
+// backslash escapes.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == ' ' || s[i] == '\t':
+			i++
+		case s[i] == '"':
+			value := strings.Builder{}
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated quoted string")
+			}
+			i++
+			tokens = append(tokens, token{value: value.String(), quoted: true})
+		default:
+			start := i
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+			tokens = append(tokens, token{value: s[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// splitFields tokenizes s and checks that it has exactly n fields.
+func splitFields(s string, n int) ([]string, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) != n {
+		return nil, fmt.Errorf("expected %d fields, got %d", n, len(tokens))
+	}
+	fields := make([]string, n)
+	for i, token := range tokens {
+		fields[i] = token.value
+	}
+	return fields, nil
+}
+
+// quote returns s as a double quoted string, escaping quotes and backslashes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parseSingleName parses records that consist of just a domain name.
+func parseSingleName(s string) (string, error) {
+	fields, err := splitFields(s, 1)
+	if err != nil {
+		return "", err
+	}
+	return parseName(fields[0])
+}
+
+// parseName checks that name is a syntactically valid domain name.
+func parseName(name string) (string, error) {
+	if name == "." {
+		return name, nil
+	}
+	trimmed := strings.TrimSuffix(name, ".")
+	if trimmed == "" || len(trimmed) > 253 {
+		return "", fmt.Errorf("'%s' is not a valid domain name", name)
+	}
+	for _, label := range strings.Split(trimmed, ".") {
+		if label == "" || len(label) > 63 {
+			return "", fmt.Errorf("'%s' is not a valid domain name", name)
+		}
+	}
+	return name, nil
+}
+
+func parseUint8(field string, s string) (uint8, error) {
+	value, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%s '%s' is not a number between 0 and 255", field, s)
+	}
+	return uint8(value), nil
+}
+
+func parseUint16(field string, s string) (uint16, error) {
+	value, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%s '%s' is not a number between 0 and 65535", field, s)
+	}
+	return uint16(value), nil
+}
+
+func parseHex(field string, s string) (string, error) {
+	if _, err := hex.DecodeString(s); err != nil || s == "" {
+		return "", fmt.Errorf("%s '%s' is not hexadecimal", field, s)
+	}
+	return s, nil
+}
@@ -0,0 +1,123 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRDataRoundTrip(t *testing.T) {
+	cases := []struct {
+		rrtype RRType
+		rdata  string
+	}{
+		{RRTypeA, "192.0.2.1"},
+		{RRTypeAAAA, "2001:db8::1"},
+		{RRTypeAAAA, "::ffff:192.0.2.1"},
+		{RRTypeCNAME, "www.example.com."},
+		{RRTypeNS, "ns1.example.net."},
+		{RRTypePTR, "host.example.com."},
+		{RRTypeMX, "10 mail.example.com."},
+		{RRTypeSRV, "10 60 5060 sip.example.com."},
+		{RRTypeTXT, "v=spf1 include:example.com ~all"},
+		{RRTypeTXT, `"part one" "part two"`},
+		{RRTypeTXT, `"say \"hi\""`},
+		{RRTypeTXT, `"C:\\path"`},
+		{RRTypeSPF, "v=spf1 -all"},
+		{RRTypeCAA, `0 issue "letsencrypt.org"`},
+		{RRTypeSOA, "ns1.example.com. admin.example.com. 2020010101 86400 7200 1209600 300"},
+		{RRTypeHINFO, `"x86_64" "Linux"`},
+		{RRTypeNAPTR, `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`},
+		{RRTypeSSHFP, "1 2 123456789abcdef67890123456789abcdef67890123456789abcdef123456789"},
+		{RRTypeTLSA, "3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6"},
+		{RRTypeDS, "12345 13 2 3A4B5C6D7E8F90A1B2C3D4E5F60718293A4B5C6D7E8F90A1B2C3D4E5F6071829"},
+		{RRTypeAPEXALIAS, "target.example.net."},
+	}
+	for _, c := range cases {
+		record, err := ParseRData(c.rrtype, c.rdata)
+		if assert.NoError(t, err, c.rdata) {
+			assert.Equal(t, c.rrtype, record.RRType())
+			assert.Equal(t, c.rdata, record.String())
+		}
+	}
+}
+
+func TestRDataParsesFields(t *testing.T) {
+	record, err := ParseRData(RRTypeSRV, "10 60 5060 sip.example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, &SRVRecord{Priority: 10, Weight: 60, Port: 5060, Target: "sip.example.com."}, record)
+
+	record, err = ParseRData(RRTypeTXT, `"a b" c`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a b", "c"}, record.(*TXTRecord).Strings)
+
+	// Unquoted CAA values are accepted, but always written quoted.
+	record, err = ParseRData(RRTypeCAA, "128 iodef mailto:security@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, `128 iodef "mailto:security@example.com"`, record.String())
+}
+
+func TestRDataRejectsInvalid(t *testing.T) {
+	cases := []struct {
+		rrtype RRType
+		rdata  string
+	}{
+		{RRTypeA, "2001:db8::1"},
+		{RRTypeA, "300.1.1.1"},
+		{RRTypeAAAA, "192.0.2.1"},
+		{RRTypeCNAME, "two names"},
+		{RRTypeCNAME, "bad..name."},
+		{RRTypeMX, "mail.example.com."},
+		{RRTypeMX, "70000 mail.example.com."},
+		{RRTypeSRV, "10 60 sip.example.com."},
+		{RRTypeTXT, " "},
+		{RRTypeTXT, `"unterminated`},
+		{RRTypeTXT, `C:\path`},
+		{RRTypeCAA, `0 is-sue "x"`},
+		{RRTypeCAA, `256 issue "x"`},
+		{RRTypeSOA, "ns1. admin. 1 2 3 4"},
+		{RRTypeHINFO, `"only-cpu"`},
+		{RRTypeSSHFP, "1 2 nothex"},
+		{RRTypeTLSA, "3 1 1"},
+		{RRTypeDS, "12345 13 2 XYZ"},
+	}
+	for _, c := range cases {
+		_, err := ParseRData(c.rrtype, c.rdata)
+		rdataErr := &RDataError{}
+		if assert.True(t, errors.As(err, &rdataErr), "%s %s", c.rrtype, c.rdata) {
+			assert.Equal(t, c.rdata, rdataErr.RData)
+		}
+	}
+}
+
+func TestRRSetRecords(t *testing.T) {
+	rrset := &RRSet{OwnerName: "@"}
+	err := rrset.SetRecords(&MXRecord{Preference: 10, Exchange: "mx1.example.com."}, &MXRecord{Preference: 20, Exchange: "mx2.example.com."})
+	assert.NoError(t, err)
+	assert.Equal(t, RRTypeMX, rrset.RRType)
+	assert.Equal(t, []string{"10 mx1.example.com.", "20 mx2.example.com."}, rrset.RData)
+
+	records, err := rrset.Records()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(20), records[1].(*MXRecord).Preference)
+
+	err = rrset.SetRecords(&ARecord{IP: net.ParseIP("192.0.2.1")}, &CNAMERecord{Target: "x."})
+	assert.Error(t, err)
+}
+
+func TestRRSetsCreateValidatesBeforeSending(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request should not have been sent: %s %s", r.Method, r.URL)
+	})
+	defer server.Close()
+
+	_, err := apiConn.RRSets().Create(context.Background(), "example.com.", &RRSet{OwnerName: "@", RRType: RRTypeMX, RData: []string{"mail.example.com."}})
+	assert.Error(t, err)
+
+	_, err = apiConn.RRSets().Replace(context.Background(), "example.com.", &RRSet{OwnerName: "@", RData: []string{"192.0.2.1"}})
+	assert.EqualError(t, err, "record set has no record type")
+}
@@ -0,0 +1,162 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/simplifi/ultradns-go/internal/ultradns"
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+)
+
+// RetryPolicy controls how APIConnection retries failed requests. Zero fields take the defaults documented on them.
+//
+// A request is retried when the connection to UltraDNS fails, or when the API answers with one of the
+// RetryableStatusCodes or RetryableErrorCodes, which applies to the token endpoint as well. Missing or rejected
+// credentials and errors of the TokenStore are not retried. Only idempotent methods (GET, PUT and DELETE) are retried unless
+// RetryNonIdempotent is set, as a POST or PATCH that failed with e.g. a 502 may still have been applied.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Default is 3.
+	MaxAttempts int
+
+	// BaseBackoff is the delay before the first retry. It doubles with every further retry. Default is 250ms.
+	BaseBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Default is 10 seconds.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1, so that many clients failing at once
+	// do not retry in lockstep. Default is 0.2; set a negative value to disable jitter.
+	Jitter float64
+
+	// RetryableStatusCodes are the HTTP status codes that are retried. Default is 500, 502, 503 and 504.
+	RetryableStatusCodes []int
+
+	// RetryableErrorCodes are UltraDNS error codes ("errorCode" in the response) that are retried regardless of the
+	// HTTP status code.
+	RetryableErrorCodes []int
+
+	// RetryNonIdempotent also retries POST and PATCH requests.
+	RetryNonIdempotent bool
+}
+
+// withDefaults returns a copy of the policy with the defaults applied. A nil policy never retries.
+func (policy *RetryPolicy) withDefaults() RetryPolicy {
+	if policy == nil {
+		return RetryPolicy{MaxAttempts: 1}
+	}
+	p := *policy
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.BaseBackoff == 0 {
+		p.BaseBackoff = 250 * time.Millisecond
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	if p.RetryableStatusCodes == nil {
+		p.RetryableStatusCodes = []int{500, 502, 503, 504}
+	}
+	return p
+}
+
+// maxAttempts returns the number of attempts allowed for the HTTP method.
+func (policy RetryPolicy) maxAttempts(method string) int {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return policy.MaxAttempts
+	default:
+		if policy.RetryNonIdempotent {
+			return policy.MaxAttempts
+		}
+		return 1
+	}
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying.
+func (policy RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	// The caller gave up, there is no point in trying again.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if resp == nil {
+		return policy.shouldRetryUnsent(err)
+	}
+	errorResponse := ultradns.ErrorResponse{}
+	return policy.isRetryableStatus(resp.StatusCode) ||
+		(errors.As(err, &errorResponse) && policy.isRetryableCode(errorResponse.ErrorCode()))
+}
+
+// shouldRetryUnsent reports whether a request that got no response is worth retrying. Either it never completed,
+// e.g. because of a connection reset, or authorizing it failed.
+func (policy RetryPolicy) shouldRetryUnsent(err error) bool {
+	// Missing or rejected credentials stay that way.
+	authErr := &ultradns.AuthorizationError{}
+	if errors.Is(err, ultradns.ErrNoCredentials) || errors.As(err, &authErr) {
+		return false
+	}
+	// The token endpoint answered with an error, which is judged like an API response.
+	apiErr := &apierror.Error{}
+	if errors.As(err, &apiErr) {
+		return policy.isRetryableStatus(apiErr.StatusCode) || policy.isRetryableCode(apiErr.Code)
+	}
+	// Otherwise only failures to reach UltraDNS are retried, not e.g. errors of the TokenStore. The http.Client
+	// reports those, and any error of the Transport, as a *url.Error.
+	urlErr := &url.Error{}
+	return errors.As(err, &urlErr)
+}
+
+// isRetryableStatus reports whether the HTTP status code is one of the RetryableStatusCodes.
+func (policy RetryPolicy) isRetryableStatus(statusCode int) bool {
+	for _, code := range policy.RetryableStatusCodes {
+		if statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableCode reports whether the UltraDNS error code is one of the RetryableErrorCodes.
+func (policy RetryPolicy) isRetryableCode(errorCode int) bool {
+	for _, code := range policy.RetryableErrorCodes {
+		if errorCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, counting from 1.
+func (policy RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(policy.BaseBackoff) * math.Pow(2, float64(retry-1))
+	if delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+	if policy.Jitter > 0 {
+		delay -= delay * math.Min(policy.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// sleep waits for the delay, returning early with the context's error if it is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
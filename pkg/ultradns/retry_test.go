@@ -0,0 +1,262 @@
+package ultradns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+// fastRetryPolicy retries quickly enough for tests.
+func fastRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 4, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+// flakyServer fails the first failures requests with the given status and UltraDNS error code, then succeeds.
+// Every request body is recorded.
+func flakyServer(t *testing.T, failures int, status int, errorCode string, bodies *[]string) (*httptest.Server, *APIConnection) {
+	attempts := 0
+	return handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*bodies = append(*bodies, string(body))
+		attempts++
+		if attempts <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"errorCode":` + errorCode + `,"errorMessage":"try again"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+}
+
+func TestRetryRecoversFromServerErrors(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 2, 503, "99999", &bodies)
+	defer server.Close()
+	apiConn.Retry = fastRetryPolicy()
+
+	resp, err := apiConn.Put("/foo", bytes.NewBufferString(`{"ttl":300}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	// The body is replayed on every attempt.
+	assert.Equal(t, []string{`{"ttl":300}`, `{"ttl":300}`, `{"ttl":300}`}, bodies)
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 10, 502, "99999", &bodies)
+	defer server.Close()
+	apiConn.Retry = fastRetryPolicy()
+
+	_, err := apiConn.Get("/foo")
+	assert.EqualError(t, err, "99999: try again")
+	assert.Len(t, bodies, 4)
+}
+
+func TestRetryDisabledByDefault(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 1, 503, "99999", &bodies)
+	defer server.Close()
+
+	_, err := apiConn.Get("/foo")
+	assert.Error(t, err)
+	assert.Len(t, bodies, 1)
+}
+
+func TestRetrySkipsNonIdempotentMethods(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 1, 503, "99999", &bodies)
+	defer server.Close()
+	apiConn.Retry = fastRetryPolicy()
+
+	_, err := apiConn.Post("/foo", bytes.NewBufferString(`{}`))
+	assert.Error(t, err)
+	assert.Len(t, bodies, 1)
+
+	// Opting in retries POST as well.
+	apiConn.Retry.RetryNonIdempotent = true
+	resp, err := apiConn.Post("/foo", bytes.NewBufferString(`{"a":1}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{`{}`, `{"a":1}`}, bodies)
+}
+
+func TestRetryOnErrorCodesOnly(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 1, 400, "12345", &bodies)
+	defer server.Close()
+	apiConn.Retry = fastRetryPolicy()
+
+	// 400 is not retryable by default.
+	_, err := apiConn.Get("/foo")
+	assert.Error(t, err)
+	assert.Len(t, bodies, 1)
+
+	bodies = nil
+	server2, apiConn2 := flakyServer(t, 1, 400, "12345", &bodies)
+	defer server2.Close()
+	apiConn2.Retry = fastRetryPolicy()
+	apiConn2.Retry.RetryableErrorCodes = []int{12345}
+	resp, err := apiConn2.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Len(t, bodies, 2)
+}
+
+func TestRetryRecoversFromConnectionErrors(t *testing.T) {
+	attempts := 0
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			// Drop the connection without answering.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+	defer server.Close()
+	apiConn.Retry = fastRetryPolicy()
+
+	resp, err := apiConn.Get("/foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, attempts)
+}
+
+// tokenServer answers every token request with the given status and body, counting them.
+func tokenServer(status int, body string, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestRetrySkipsMissingCredentials(t *testing.T) {
+	calls := 0
+	apiConn := NewAPIConnection(&APIOptions{
+		Credentials: CredentialsFunc(func(ctx context.Context) (*Credentials, error) {
+			calls++
+			return nil, ErrNoCredentials
+		}),
+		Retry: fastRetryPolicy(),
+	})
+
+	_, err := apiConn.Get("/foo")
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetrySkipsRejectedCredentials(t *testing.T) {
+	calls := 0
+	server := tokenServer(400, `{"error":"invalid_grant","error_description":"invalid_grant: bad credentials"}`, &calls)
+	defer server.Close()
+	apiConn := NewAPIConnection(&APIOptions{
+		Username:     validUsername,
+		Password:     "wrong",
+		RefreshToken: "expired",
+		BaseURL:      server.URL,
+		Retry:        fastRetryPolicy(),
+	})
+
+	_, err := apiConn.Get("/foo")
+	authErr := &AuthorizationError{}
+	assert.True(t, errors.As(err, &authErr))
+	// The refresh token and the password, once each.
+	assert.Equal(t, 2, calls)
+}
+
+// failingTokenStore is a TokenStore whose Load always fails.
+type failingTokenStore struct {
+	loads int
+}
+
+func (s *failingTokenStore) Load() (*Tokens, error) {
+	s.loads++
+	return nil, errors.New("corrupt token file")
+}
+
+func (s *failingTokenStore) Save(tokens *Tokens) error {
+	return nil
+}
+
+func (s *failingTokenStore) Lock(ctx context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+func TestRetrySkipsTokenStoreErrors(t *testing.T) {
+	store := &failingTokenStore{}
+	apiConn := NewAPIConnection(&APIOptions{
+		Username:   validUsername,
+		Password:   validPassword,
+		TokenStore: store,
+		Retry:      fastRetryPolicy(),
+	})
+
+	_, err := apiConn.Get("/foo")
+	assert.EqualError(t, err, "corrupt token file")
+	assert.Equal(t, 1, store.loads)
+}
+
+func TestRetryTokenEndpointErrorsByStatus(t *testing.T) {
+	calls := 0
+	server := tokenServer(403, `<html>Forbidden</html>`, &calls)
+	defer server.Close()
+	apiConn := NewAPIConnection(&APIOptions{
+		Username: validUsername,
+		Password: validPassword,
+		BaseURL:  server.URL,
+		Retry:    fastRetryPolicy(),
+	})
+
+	_, err := apiConn.Get("/foo")
+	assert.True(t, errors.Is(err, apierror.Unauthorized))
+	assert.Equal(t, 1, calls)
+
+	// An unavailable token endpoint is retried like any other request.
+	calls = 0
+	server2 := tokenServer(503, `<html>Service Unavailable</html>`, &calls)
+	defer server2.Close()
+	apiConn.BaseURL = server2.URL
+	apiConn.Authorization.BaseURL = server2.URL
+
+	_, err = apiConn.Get("/foo")
+	assert.True(t, errors.Is(err, apierror.ServerError))
+	assert.Equal(t, 4, calls)
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	var bodies []string
+	server, apiConn := flakyServer(t, 10, 503, "99999", &bodies)
+	defer server.Close()
+	apiConn.Retry = &RetryPolicy{MaxAttempts: 10, BaseBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := apiConn.GetContext(ctx, "/foo")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+	assert.Len(t, bodies, 1)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := (&RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: -1}).withDefaults()
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, time.Second, policy.backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		assert.True(t, delay > 100*time.Millisecond && delay <= 200*time.Millisecond, "delay %s out of range", delay)
+	}
+}
@@ -0,0 +1,236 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+)
+
+// RRSet is a resource record set: all the records of a single type at a single owner name.
+type RRSet struct {
+	// OwnerName may be given relative to the zone ("www") or absolute ("www.example.com."). UltraDNS always returns
+	// absolute names.
+	OwnerName string `json:"ownerName,omitempty"`
+	RRType    RRType `json:"rrtype,omitempty"`
+	TTL       int    `json:"ttl,omitempty"`
+	// RData holds the records in presentation format, e.g. "10 mail.example.com." for an MX record.
+	RData []string `json:"rdata,omitempty"`
+	// Profile is set for record sets that are pools, e.g. Traffic Controller pools. It is passed along unchanged.
+	Profile json.RawMessage `json:"profile,omitempty"`
+}
+
+// RRSetList is a single page of a record set listing.
+type RRSetList struct {
+	ZoneName   string     `json:"zoneName"`
+	RRSets     []RRSet    `json:"rrSets"`
+	QueryInfo  QueryInfo  `json:"queryInfo"`
+	ResultInfo ResultInfo `json:"resultInfo"`
+}
+
+// AbsoluteOwnerName returns owner as a fully qualified name with a trailing dot. Names without a trailing dot are
+// treated as relative to zone, unless they already end in the zone name. "" and "@" refer to the zone apex.
+func AbsoluteOwnerName(owner string, zone string) string {
+	zone = strings.TrimSuffix(zone, ".")
+	switch {
+	case owner == "" || owner == "@":
+		return zone + "."
+	case strings.HasSuffix(owner, "."):
+		return owner
+	case strings.EqualFold(owner, zone) || hasSuffixFold(owner, "."+zone):
+		return owner + "."
+	default:
+		return owner + "." + zone + "."
+	}
+}
+
+// RelativeOwnerName returns owner relative to zone, e.g. "www" for "www.example.com." in "example.com". The apex is
+// returned as "@". Names outside of the zone are returned as absolute names.
+func RelativeOwnerName(owner string, zone string) string {
+	absolute := AbsoluteOwnerName(owner, zone)
+	apex := strings.TrimSuffix(zone, ".") + "."
+	switch {
+	case strings.EqualFold(absolute, apex):
+		return "@"
+	case hasSuffixFold(absolute, "."+apex):
+		return absolute[:len(absolute)-len(apex)-1]
+	default:
+		return absolute
+	}
+}
+
+// hasSuffixFold is strings.HasSuffix, ignoring case as DNS names do.
+func hasSuffixFold(s string, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
+
+// RRSetPath returns the API path of the record set of the given type at owner, e.g.
+// "/zones/example.com./rrsets/A/www.example.com.". owner is normalized with AbsoluteOwnerName.
+func RRSetPath(zone string, rrtype RRType, owner string) string {
+	return rrsetsPath(zone) + "/" + rrtype.pathSegment() + "/" + url.PathEscape(AbsoluteOwnerName(owner, zone))
+}
+
+// rrsetsPath returns the API path of all record sets in the zone.
+func rrsetsPath(zone string) string {
+	return zonePath(zone) + "/rrsets"
+}
+
+// RRSetsService provides typed access to the /zones/{zone}/rrsets endpoints. Like those of ZonesService, its changes
+// return the ID of their task, if any.
+type RRSetsService struct {
+	apiConn *APIConnection
+}
+
+// RRSets returns the service for managing record sets over this connection.
+func (apiConn *APIConnection) RRSets() *RRSetsService {
+	return &RRSetsService{apiConn: apiConn}
+}
+
+// List returns a single page of the record sets in the zone. opts may be nil.
+func (s *RRSetsService) List(ctx context.Context, zone string, opts *ListOptions) (*RRSetList, error) {
+	return s.list(ctx, rrsetsPath(zone), opts)
+}
+
+// ListByType returns a single page of the record sets of the given type in the zone. opts may be nil.
+func (s *RRSetsService) ListByType(ctx context.Context, zone string, rrtype RRType, opts *ListOptions) (*RRSetList, error) {
+	return s.list(ctx, rrsetsPath(zone)+"/"+rrtype.pathSegment(), opts)
+}
+
+// list fetches a single page of record sets from path.
+func (s *RRSetsService) list(ctx context.Context, path string, opts *ListOptions) (*RRSetList, error) {
+	list := &RRSetList{}
+	if err := s.apiConn.GetJSONContext(ctx, withQuery(path, opts.values()), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListAll returns every record set in the zone matching opts, following the pagination. opts may be nil.
+func (s *RRSetsService) ListAll(ctx context.Context, zone string, opts *ListOptions) ([]RRSet, error) {
+	var rrsets []RRSet
+	err := s.apiConn.Paginate(rrsetsPath(zone), opts.values()).Walk(ctx, func(page *Page) error {
+		list := RRSetList{}
+		if err := page.Decode(&list); err != nil {
+			return err
+		}
+		rrsets = append(rrsets, list.RRSets...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rrsets, nil
+}
+
+// Get fetches the record set of the given type at owner. owner may be relative to the zone.
+func (s *RRSetsService) Get(ctx context.Context, zone string, rrtype RRType, owner string) (*RRSet, error) {
+	list, err := s.list(ctx, RRSetPath(zone, rrtype, owner), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.RRSets) == 0 {
+		return nil, fmt.Errorf("no %s record set at %s: %w", rrtype, AbsoluteOwnerName(owner, zone), apierror.NotFound)
+	}
+	return &list.RRSets[0], nil
+}
+
+// Create creates a new record set in the zone. Fails if a record set of the same type already exists at the owner.
+func (s *RRSetsService) Create(ctx context.Context, zone string, rrset *RRSet) (string, error) {
+	return s.send(ctx, "POST", zone, rrset)
+}
+
+// Replace replaces an existing record set with the given one.
+func (s *RRSetsService) Replace(ctx context.Context, zone string, rrset *RRSet) (string, error) {
+	return s.send(ctx, "PUT", zone, rrset)
+}
+
+// Patch partially updates an existing record set; only the fields that are set are changed.
+// For fine grained changes, e.g. to a single record of the set, use JSONPatch or Update.
+func (s *RRSetsService) Patch(ctx context.Context, zone string, rrset *RRSet) (string, error) {
+	return s.send(ctx, "PATCH", zone, rrset)
+}
+
+// JSONPatch applies a JSON Patch to the record set of the given type at owner.
+func (s *RRSetsService) JSONPatch(ctx context.Context, zone string, rrtype RRType, owner string, patch jsonpatch.Patch) (string, error) {
+	body, err := patch.Reader()
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.apiConn.JSONPatchContext(ctx, RRSetPath(zone, rrtype, owner), body)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
+
+// Update changes the record set from current to desired by sending a JSON Patch of their differences, so that
+// fields and records that did not change are left alone. Nothing is sent if they are equal.
+// current is typically the result of Get; its owner and type identify the record set. Only the fields set on desired
+// are compared: a zero TTL, empty RData or nil Profile keep the current value, so e.g. updating the records of a pool
+// does not remove its profile.
+func (s *RRSetsService) Update(ctx context.Context, zone string, current *RRSet, desired *RRSet) (string, error) {
+	target := *desired
+	if target.TTL == 0 {
+		target.TTL = current.TTL
+	}
+	if len(target.RData) == 0 {
+		target.RData = current.RData
+	}
+	if target.Profile == nil {
+		target.Profile = current.Profile
+	}
+	// The owner and type cannot be patched, so only their spelling may differ.
+	if target.RRType == 0 {
+		target.RRType = current.RRType
+	}
+	if target.OwnerName == "" {
+		target.OwnerName = current.OwnerName
+	}
+	if target.RRType != current.RRType {
+		return "", fmt.Errorf("cannot change the type of a record set from %s to %s", current.RRType, target.RRType)
+	}
+	if !strings.EqualFold(AbsoluteOwnerName(target.OwnerName, zone), AbsoluteOwnerName(current.OwnerName, zone)) {
+		return "", fmt.Errorf("cannot rename record set %s to %s", current.OwnerName, target.OwnerName)
+	}
+	target.OwnerName = current.OwnerName
+	if err := target.Validate(); err != nil {
+		return "", err
+	}
+	patch, err := jsonpatch.Diff(current, &target)
+	if err != nil {
+		return "", err
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+	return s.JSONPatch(ctx, zone, current.RRType, current.OwnerName, patch)
+}
+
+// send validates the record set and sends it to its path with the given method.
+func (s *RRSetsService) send(ctx context.Context, method string, zone string, rrset *RRSet) (string, error) {
+	if err := rrset.Validate(); err != nil {
+		return "", err
+	}
+	body, err := encodeJSON(rrset)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.apiConn.request(ctx, method, RRSetPath(zone, rrset.RRType, rrset.OwnerName), "application/json", body)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
+
+// Delete deletes the record set of the given type at owner.
+func (s *RRSetsService) Delete(ctx context.Context, zone string, rrtype RRType, owner string) (string, error) {
+	resp, err := s.apiConn.DeleteContext(ctx, RRSetPath(zone, rrtype, owner))
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
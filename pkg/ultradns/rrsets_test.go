@@ -0,0 +1,176 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsoluteOwnerName(t *testing.T) {
+	cases := []struct{ owner, zone, expected string }{
+		{"www", "example.com.", "www.example.com."},
+		{"www", "example.com", "www.example.com."},
+		{"www.example.com", "example.com.", "www.example.com."},
+		{"WWW.Example.COM", "example.com.", "WWW.Example.COM."},
+		{"www.example.com.", "example.com.", "www.example.com."},
+		{"other.net.", "example.com.", "other.net."},
+		{"@", "example.com.", "example.com."},
+		{"", "example.com", "example.com."},
+		{"example.com", "example.com.", "example.com."},
+		{"notexample.com", "example.com.", "notexample.com.example.com."},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, AbsoluteOwnerName(c.owner, c.zone), c.owner)
+	}
+}
+
+func TestRelativeOwnerName(t *testing.T) {
+	assert.Equal(t, "www", RelativeOwnerName("www.example.com.", "example.com."))
+	assert.Equal(t, "a.b", RelativeOwnerName("a.b", "example.com"))
+	assert.Equal(t, "@", RelativeOwnerName("example.com.", "example.com."))
+	assert.Equal(t, "other.net.", RelativeOwnerName("other.net.", "example.com."))
+}
+
+func TestRRSetPath(t *testing.T) {
+	assert.Equal(t, "/zones/example.com./rrsets/A/www.example.com.", RRSetPath("example.com.", RRTypeA, "www"))
+	assert.Equal(t, "/zones/example.com/rrsets/9/example.com.", RRSetPath("example.com", RRType(9), "@"))
+}
+
+func TestRRSetsGet(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/example.com./rrsets/MX/example.com.", r.URL.Path)
+		w.Write([]byte(`{"zoneName":"example.com.","rrSets":[{"ownerName":"example.com.","rrtype":"MX (15)","ttl":300,"rdata":["10 mail.example.com."]}],"queryInfo":{"sort":"OWNER","reverse":false,"limit":100},"resultInfo":{"totalCount":1,"offset":0,"returnedCount":1}}`))
+	})
+	defer server.Close()
+
+	rrset, err := apiConn.RRSets().Get(context.Background(), "example.com.", RRTypeMX, "@")
+	assert.NoError(t, err)
+	assert.Equal(t, RRTypeMX, rrset.RRType)
+	assert.Equal(t, 300, rrset.TTL)
+	assert.Equal(t, []string{"10 mail.example.com."}, rrset.RData)
+}
+
+func TestRRSetsGetEmptyIsNotFound(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"zoneName":"example.com.","rrSets":[],"queryInfo":{},"resultInfo":{"totalCount":0,"offset":0,"returnedCount":0}}`))
+	})
+	defer server.Close()
+
+	_, err := apiConn.RRSets().Get(context.Background(), "example.com.", RRTypeA, "www")
+	assert.True(t, errors.Is(err, apierror.NotFound))
+	assert.Contains(t, err.Error(), "no A record set at www.example.com.")
+}
+
+func TestRRSetsListAll(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/example.com./rrsets", r.URL.Path)
+		assert.Equal(t, "kind:RECORDS", r.URL.Query().Get("q"))
+		if r.URL.Query().Get("offset") == "0" {
+			w.Write([]byte(`{"zoneName":"example.com.","rrSets":[{"ownerName":"a.example.com.","rrtype":"A (1)","rdata":["1.1.1.1"]}],"resultInfo":{"totalCount":2,"offset":0,"returnedCount":1}}`))
+		} else {
+			w.Write([]byte(`{"zoneName":"example.com.","rrSets":[{"ownerName":"b.example.com.","rrtype":"AAAA (28)","rdata":["::1"]}],"resultInfo":{"totalCount":2,"offset":1,"returnedCount":1}}`))
+		}
+	})
+	defer server.Close()
+
+	rrsets, err := apiConn.RRSets().ListAll(context.Background(), "example.com.", &ListOptions{Q: "kind:RECORDS"})
+	assert.NoError(t, err)
+	assert.Len(t, rrsets, 2)
+	assert.Equal(t, RRTypeAAAA, rrsets[1].RRType)
+}
+
+func TestRRSetsWrites(t *testing.T) {
+	type call struct{ method, path string }
+	var calls []call
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, call{r.Method, r.URL.Path})
+		if r.Method != "DELETE" {
+			rrset := RRSet{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&rrset))
+			assert.Equal(t, []string{"1.2.3.4"}, rrset.RData)
+		}
+		w.Write([]byte(`{"message":"Successful"}`))
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	rrset := &RRSet{OwnerName: "www", RRType: RRTypeA, TTL: 60, RData: []string{"1.2.3.4"}}
+	_, err := apiConn.RRSets().Create(ctx, "example.com.", rrset)
+	assert.NoError(t, err)
+	_, err = apiConn.RRSets().Replace(ctx, "example.com.", rrset)
+	assert.NoError(t, err)
+	_, err = apiConn.RRSets().Patch(ctx, "example.com.", rrset)
+	assert.NoError(t, err)
+	_, err = apiConn.RRSets().Delete(ctx, "example.com.", RRTypeA, "www")
+	assert.NoError(t, err)
+
+	path := "/zones/example.com./rrsets/A/www.example.com."
+	assert.Equal(t, []call{{"POST", path}, {"PUT", path}, {"PATCH", path}, {"DELETE", path}}, calls)
+}
+
+func TestRRSetsUpdateSendsDiff(t *testing.T) {
+	var patches []string
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		assert.Equal(t, "/zones/example.com./rrsets/A/www.example.com.", r.URL.Path)
+		assert.Equal(t, "application/json-patch+json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		patches = append(patches, string(body))
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	current := &RRSet{OwnerName: "www.example.com.", RRType: RRTypeA, TTL: 300, RData: []string{"192.0.2.1", "192.0.2.2"}}
+	desired := &RRSet{OwnerName: "www", TTL: 300, RData: []string{"192.0.2.2", "192.0.2.3"}}
+
+	_, err := apiConn.RRSets().Update(ctx, "example.com.", current, desired)
+	assert.NoError(t, err)
+	// Equal record sets don't send anything.
+	_, err = apiConn.RRSets().Update(ctx, "example.com.", current, current)
+	assert.NoError(t, err)
+	assert.Len(t, patches, 1)
+	assert.JSONEq(t, `[{"op":"remove","path":"/rdata/0"},{"op":"add","path":"/rdata/1","value":"192.0.2.3"}]`, patches[0])
+
+	_, err = apiConn.RRSets().Update(ctx, "example.com.", current, &RRSet{OwnerName: "other", RData: current.RData})
+	assert.Error(t, err)
+	_, err = apiConn.RRSets().Update(ctx, "example.com.", current, &RRSet{OwnerName: "www", RRType: RRTypeAAAA, RData: []string{"::1"}})
+	assert.Error(t, err)
+	_, err = apiConn.RRSets().Update(ctx, "example.com.", current, &RRSet{OwnerName: "www", RData: []string{"bogus"}})
+	assert.Error(t, err)
+	assert.Len(t, patches, 1)
+}
+
+func TestRRSetsUpdateKeepsUnsetFields(t *testing.T) {
+	var patches []string
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		patches = append(patches, string(body))
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	current := &RRSet{
+		OwnerName: "pool.example.com.",
+		RRType:    RRTypeA,
+		TTL:       300,
+		RData:     []string{"192.0.2.1"},
+		Profile:   json.RawMessage(`{"@context":"http://schemas.ultradns.com/TCPool.jsonschema"}`),
+	}
+	// Neither the TTL nor the profile are set, so only the records change.
+	desired := &RRSet{RData: []string{"192.0.2.2"}}
+
+	_, err := apiConn.RRSets().Update(ctx, "example.com.", current, desired)
+	assert.NoError(t, err)
+	_, err = apiConn.RRSets().Update(ctx, "example.com.", current, &RRSet{TTL: 300})
+	assert.NoError(t, err)
+	assert.Len(t, patches, 1)
+	assert.JSONEq(t, `[{"op":"replace","path":"/rdata/0","value":"192.0.2.2"}]`, patches[0])
+}
@@ -0,0 +1,129 @@
+package ultradns
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RRType is a DNS resource record type, e.g. RRTypeA.
+// UltraDNS refers to record types by name ("A"), by number ("1"), and in responses by both ("A (1)"). ParseRRType
+// accepts all of these forms.
+type RRType uint16
+
+// Record types supported by UltraDNS.
+const (
+	RRTypeA     RRType = 1
+	RRTypeNS    RRType = 2
+	RRTypeCNAME RRType = 5
+	RRTypeSOA   RRType = 6
+	RRTypePTR   RRType = 12
+	RRTypeHINFO RRType = 13
+	RRTypeMX    RRType = 15
+	RRTypeTXT   RRType = 16
+	RRTypeRP    RRType = 17
+	RRTypeAAAA  RRType = 28
+	RRTypeSRV   RRType = 33
+	RRTypeNAPTR RRType = 35
+	RRTypeDS    RRType = 43
+	RRTypeSSHFP RRType = 44
+	RRTypeTLSA  RRType = 52
+	RRTypeSPF   RRType = 99
+	RRTypeCAA   RRType = 257
+	// RRTypeAPEXALIAS is the UltraDNS specific alias record allowed at the zone apex.
+	RRTypeAPEXALIAS RRType = 65282
+)
+
+var rrTypeNames = map[RRType]string{
+	RRTypeA:         "A",
+	RRTypeNS:        "NS",
+	RRTypeCNAME:     "CNAME",
+	RRTypeSOA:       "SOA",
+	RRTypePTR:       "PTR",
+	RRTypeHINFO:     "HINFO",
+	RRTypeMX:        "MX",
+	RRTypeTXT:       "TXT",
+	RRTypeRP:        "RP",
+	RRTypeAAAA:      "AAAA",
+	RRTypeSRV:       "SRV",
+	RRTypeNAPTR:     "NAPTR",
+	RRTypeDS:        "DS",
+	RRTypeSSHFP:     "SSHFP",
+	RRTypeTLSA:      "TLSA",
+	RRTypeSPF:       "SPF",
+	RRTypeCAA:       "CAA",
+	RRTypeAPEXALIAS: "APEXALIAS",
+}
+
+var rrTypesByName = func() map[string]RRType {
+	byName := make(map[string]RRType, len(rrTypeNames))
+	for rrtype, name := range rrTypeNames {
+		byName[name] = rrtype
+	}
+	return byName
+}()
+
+// ParseRRType parses a record type given as a name ("A"), a number ("1"), the UltraDNS response form ("A (1)") or
+// the RFC 3597 form ("TYPE1"). Names are case-insensitive.
+func ParseRRType(s string) (RRType, error) {
+	s = strings.TrimSpace(s)
+
+	// "A (1)": the number is authoritative, which also lets us decode types we have no name for.
+	if open := strings.LastIndex(s, "("); open >= 0 && strings.HasSuffix(s, ")") {
+		return parseRRTypeNumber(s, strings.TrimSpace(s[open+1:len(s)-1]))
+	}
+
+	upper := strings.ToUpper(s)
+	if rrtype, ok := rrTypesByName[upper]; ok {
+		return rrtype, nil
+	}
+	if strings.HasPrefix(upper, "TYPE") {
+		return parseRRTypeNumber(s, upper[len("TYPE"):])
+	}
+	return parseRRTypeNumber(s, s)
+}
+
+// parseRRTypeNumber parses the numeric part of a record type. original is only used for the error message.
+func parseRRTypeNumber(original string, number string) (RRType, error) {
+	value, err := strconv.ParseUint(number, 10, 16)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("unknown record type '%s'", original)
+	}
+	return RRType(value), nil
+}
+
+// String returns the record type's name, or the RFC 3597 "TYPE<n>" form for types without a known name.
+func (rrtype RRType) String() string {
+	if name, ok := rrTypeNames[rrtype]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(rrtype))
+}
+
+// pathSegment returns the form UltraDNS accepts in URLs: the name when known, otherwise the number.
+func (rrtype RRType) pathSegment() string {
+	if name, ok := rrTypeNames[rrtype]; ok {
+		return name
+	}
+	return strconv.Itoa(int(rrtype))
+}
+
+// MarshalJSON encodes the record type by name.
+func (rrtype RRType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rrtype.pathSegment())
+}
+
+// UnmarshalJSON decodes any of the forms accepted by ParseRRType.
+func (rrtype *RRType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseRRType(s)
+	if err != nil {
+		return err
+	}
+	*rrtype = parsed
+	return nil
+}
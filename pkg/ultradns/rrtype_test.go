@@ -0,0 +1,51 @@
+package ultradns
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRRType(t *testing.T) {
+	cases := map[string]RRType{
+		"A":           RRTypeA,
+		"aaaa":        RRTypeAAAA,
+		"A (1)":       RRTypeA,
+		"MX (15)":     RRTypeMX,
+		"15":          RRTypeMX,
+		"TYPE257":     RRTypeCAA,
+		" CNAME ":     RRTypeCNAME,
+		"UNKNOWN (9)": RRType(9),
+	}
+	for input, expected := range cases {
+		rrtype, err := ParseRRType(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, rrtype, input)
+	}
+
+	for _, input := range []string{"", "BOGUS", "0", "70000", "A ()"} {
+		_, err := ParseRRType(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRRTypeString(t *testing.T) {
+	assert.Equal(t, "SRV", RRTypeSRV.String())
+	assert.Equal(t, "TYPE9", RRType(9).String())
+}
+
+func TestRRTypeJSON(t *testing.T) {
+	rrset := RRSet{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"ownerName":"www.example.com.","rrtype":"A (1)","rdata":["1.2.3.4"]}`), &rrset))
+	assert.Equal(t, RRTypeA, rrset.RRType)
+
+	encoded, err := json.Marshal(rrset)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ownerName":"www.example.com.","rrtype":"A","rdata":["1.2.3.4"]}`, string(encoded))
+
+	// The type is usually part of the URL, so an unset type is left out of request bodies.
+	encoded, err = json.Marshal(RRSet{TTL: 300})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ttl":300}`, string(encoded))
+}
@@ -0,0 +1,160 @@
+package ultradns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TaskStatus is the state of an asynchronous task.
+type TaskStatus string
+
+// States of an asynchronous task.
+const (
+	TaskPending   TaskStatus = "PENDING"
+	TaskInProcess TaskStatus = "IN_PROCESS"
+	TaskComplete  TaskStatus = "COMPLETE"
+	TaskFailed    TaskStatus = "ERROR"
+)
+
+// Task is an operation that UltraDNS processes asynchronously. Requests starting one are answered with
+// 202 Accepted and the task's ID in the X-Task-Id header.
+type Task struct {
+	TaskID  string     `json:"taskId"`
+	Code    TaskStatus `json:"code"`
+	Message string     `json:"message,omitempty"`
+	// ResultURI is where the result of a completed task can be fetched, if it has one.
+	ResultURI string `json:"resultUri,omitempty"`
+}
+
+// Done returns true once the task has completed or failed.
+func (task *Task) Done() bool {
+	return task.Code == TaskComplete || task.Code == TaskFailed
+}
+
+// TaskList is a page of tasks.
+type TaskList struct {
+	QueryInfo  QueryInfo  `json:"queryInfo"`
+	ResultInfo ResultInfo `json:"resultInfo"`
+	Tasks      []Task     `json:"tasks"`
+}
+
+// TaskError is returned when a task ended in the ERROR state.
+type TaskError struct {
+	Task Task
+}
+
+// Error is the interface for the error type.
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s failed: %s", e.Task.TaskID, e.Task.Message)
+}
+
+// TaskID returns the ID of the task started by the request, or "" if the request was processed synchronously.
+func TaskID(resp *http.Response) string {
+	if resp.StatusCode != http.StatusAccepted {
+		return ""
+	}
+	return resp.Header.Get("X-Task-Id")
+}
+
+// TasksService provides access to asynchronous tasks.
+type TasksService struct {
+	apiConn *APIConnection
+
+	// PollInterval is the delay before polling a task again in Wait. It doubles after every poll. Default is 500ms.
+	PollInterval time.Duration
+
+	// MaxPollInterval caps the delay between polls. Default is 10 seconds.
+	MaxPollInterval time.Duration
+}
+
+// Tasks returns the service for tracking asynchronous tasks over this connection.
+func (apiConn *APIConnection) Tasks() *TasksService {
+	return &TasksService{apiConn: apiConn}
+}
+
+// Get fetches the current state of a task.
+func (s *TasksService) Get(ctx context.Context, taskID string) (*Task, error) {
+	task := &Task{}
+	if err := s.apiConn.GetJSONContext(ctx, taskPath(taskID), task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// List returns a page of the account's tasks, e.g. with Q set to "status:ERROR".
+func (s *TasksService) List(ctx context.Context, opts *ListOptions) (*TaskList, error) {
+	list := &TaskList{}
+	if err := s.apiConn.GetJSONContext(ctx, withQuery("/tasks", opts.values()), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Delete removes a finished task from the task list.
+func (s *TasksService) Delete(ctx context.Context, taskID string) error {
+	return s.apiConn.DeleteJSONContext(ctx, taskPath(taskID), nil)
+}
+
+// Result decodes the result of a completed task into v.
+func (s *TasksService) Result(ctx context.Context, task *Task, v interface{}) error {
+	path := task.ResultURI
+	if path == "" {
+		path = taskPath(task.TaskID) + "/result"
+	}
+	// The result URI may be absolute, while the verb methods expect a path below the BaseURL.
+	path = strings.TrimPrefix(path, s.apiConn.BaseURL)
+	return s.apiConn.GetJSONContext(ctx, path, v)
+}
+
+// Wait polls the task, backing off between polls, until it is done or the context is done. It returns the final
+// task, along with a *TaskError if the task failed.
+func (s *TasksService) Wait(ctx context.Context, taskID string) (*Task, error) {
+	interval, maxInterval := s.PollInterval, s.MaxPollInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	if maxInterval <= 0 {
+		maxInterval = 10 * time.Second
+	}
+	for {
+		task, err := s.Get(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if task.Code == TaskFailed {
+			return task, &TaskError{Task: *task}
+		}
+		if task.Done() {
+			return task, nil
+		}
+		if err = sleep(ctx, interval); err != nil {
+			return task, err
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// taskPath returns the path of a task.
+func taskPath(taskID string) string {
+	return "/tasks/" + url.PathEscape(taskID)
+}
+
+// complete discards the body of a successful response to a change and returns the ID of the task the API started for
+// it, or "" if it was processed synchronously. If WaitForTasks is set, it then waits for the task to finish.
+func (apiConn *APIConnection) complete(ctx context.Context, resp *http.Response) (string, error) {
+	if err := apiConn.decodeJSON(resp, nil); err != nil {
+		return "", err
+	}
+	taskID := TaskID(resp)
+	if taskID == "" || !apiConn.WaitForTasks {
+		return taskID, nil
+	}
+	_, err := apiConn.Tasks().Wait(ctx, taskID)
+	return taskID, err
+}
@@ -0,0 +1,171 @@
+package ultradns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksGetAndList(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tasks/abc-123":
+			w.Write([]byte(`{"taskId":"abc-123","code":"IN_PROCESS","message":"Processing"}`))
+		case "/tasks":
+			assert.Equal(t, "status:ERROR", r.URL.Query().Get("q"))
+			w.Write([]byte(`{"tasks":[{"taskId":"abc-123","code":"ERROR","message":"Failed"}],"resultInfo":{"totalCount":1,"returnedCount":1}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+	defer server.Close()
+	tasks := apiConn.Tasks()
+
+	task, err := tasks.Get(context.Background(), "abc-123")
+	assert.NoError(t, err)
+	assert.Equal(t, &Task{TaskID: "abc-123", Code: TaskInProcess, Message: "Processing"}, task)
+	assert.False(t, task.Done())
+
+	list, err := tasks.List(context.Background(), &ListOptions{Q: "status:ERROR"})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Tasks, 1) {
+		assert.Equal(t, TaskFailed, list.Tasks[0].Code)
+		assert.True(t, list.Tasks[0].Done())
+	}
+}
+
+func TestTasksDeleteAndResult(t *testing.T) {
+	var methods []string
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		if r.Method == "GET" {
+			w.Write([]byte(`{"zoneName":"example.com."}`))
+		}
+	})
+	defer server.Close()
+	tasks := apiConn.Tasks()
+
+	assert.NoError(t, tasks.Delete(context.Background(), "abc-123"))
+
+	result := struct {
+		ZoneName string `json:"zoneName"`
+	}{}
+	assert.NoError(t, tasks.Result(context.Background(), &Task{TaskID: "abc-123"}, &result))
+	assert.NoError(t, tasks.Result(context.Background(), &Task{TaskID: "abc-123", ResultURI: server.URL + "/tasks/abc-123/export"}, &result))
+	assert.Equal(t, "example.com.", result.ZoneName)
+	assert.Equal(t, []string{"DELETE /tasks/abc-123", "GET /tasks/abc-123/result", "GET /tasks/abc-123/export"}, methods)
+}
+
+// taskServer answers every change with 202 Accepted, and reports the task as pending for the given number of polls
+// before it ends with the final code.
+func taskServer(t *testing.T, polls int32, final string) (*httptest.Server, *APIConnection, *int32) {
+	var count int32
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tasks/abc-123" {
+			w.Header().Set("X-Task-Id", "abc-123")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"message":"Pending"}`))
+			return
+		}
+		if atomic.AddInt32(&count, 1) <= polls {
+			w.Write([]byte(`{"taskId":"abc-123","code":"PENDING"}`))
+			return
+		}
+		w.Write([]byte(`{"taskId":"abc-123","code":"` + final + `","message":"Done"}`))
+	})
+	return server, apiConn, &count
+}
+
+func TestTasksWait(t *testing.T) {
+	server, apiConn, polls := taskServer(t, 2, "COMPLETE")
+	defer server.Close()
+	tasks := apiConn.Tasks()
+	tasks.PollInterval = time.Millisecond
+
+	task, err := tasks.Wait(context.Background(), "abc-123")
+	assert.NoError(t, err)
+	assert.Equal(t, TaskComplete, task.Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(polls))
+}
+
+func TestTasksWaitFailed(t *testing.T) {
+	server, apiConn, _ := taskServer(t, 0, "ERROR")
+	defer server.Close()
+
+	task, err := apiConn.Tasks().Wait(context.Background(), "abc-123")
+	taskErr := &TaskError{}
+	if assert.True(t, errors.As(err, &taskErr)) {
+		assert.Equal(t, "abc-123", taskErr.Task.TaskID)
+	}
+	assert.EqualError(t, err, "task abc-123 failed: Done")
+	assert.Equal(t, TaskFailed, task.Code)
+}
+
+func TestTasksWaitHonorsContext(t *testing.T) {
+	server, apiConn, _ := taskServer(t, 1000, "COMPLETE")
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := apiConn.Tasks().Wait(ctx, "abc-123")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+}
+
+func TestWaitForTasks(t *testing.T) {
+	server, apiConn, polls := taskServer(t, 0, "COMPLETE")
+	defer server.Close()
+
+	// Without WaitForTasks, the change returns once accepted.
+	taskID, err := apiConn.Zones().Delete(context.Background(), "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc-123", taskID)
+	assert.Equal(t, int32(0), atomic.LoadInt32(polls))
+
+	apiConn.WaitForTasks = true
+	taskID, err = apiConn.Zones().Delete(context.Background(), "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc-123", taskID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(polls))
+}
+
+func TestCreateReturnsTaskID(t *testing.T) {
+	server, apiConn, polls := taskServer(t, 0, "COMPLETE")
+	defer server.Close()
+
+	taskID, err := apiConn.Zones().CreatePrimary(context.Background(), "example.com.", "acct")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc-123", taskID)
+	assert.Equal(t, int32(0), atomic.LoadInt32(polls))
+
+	// The ID is enough to wait for this change alone.
+	task, err := apiConn.Tasks().Wait(context.Background(), taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskComplete, task.Code)
+}
+
+func TestSynchronousChangeHasNoTaskID(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"Successful"}`))
+	})
+	defer server.Close()
+	apiConn.WaitForTasks = true
+
+	taskID, err := apiConn.RRSets().Delete(context.Background(), "example.com.", RRTypeA, "www")
+	assert.NoError(t, err)
+	assert.Equal(t, "", taskID)
+}
+
+func TestWaitForTasksFailed(t *testing.T) {
+	server, apiConn, _ := taskServer(t, 0, "ERROR")
+	defer server.Close()
+	apiConn.WaitForTasks = true
+
+	_, err := apiConn.RRSets().Delete(context.Background(), "example.com.", RRTypeA, "www")
+	assert.True(t, errors.As(err, new(*TaskError)))
+}
@@ -0,0 +1,285 @@
+package ultradns
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+)
+
+// TCPoolContext is the "@context" that identifies a Traffic Controller pool profile.
+const TCPoolContext = "http://schemas.ultradns.com/TCPool.jsonschema"
+
+// States of a Traffic Controller pool member.
+const (
+	// TCPoolStateNormal lets UltraDNS decide whether to serve the record based on its probes.
+	TCPoolStateNormal = "NORMAL"
+	// TCPoolStateActive always serves the record.
+	TCPoolStateActive = "ACTIVE"
+	// TCPoolStateInactive never serves the record.
+	TCPoolStateInactive = "INACTIVE"
+)
+
+// TCPool is a Traffic Controller pool: a record set whose records are served based on their state, priority and
+// probe results. RData[i] is configured by Profile.RDataInfo[i].
+type TCPool struct {
+	OwnerName string        `json:"ownerName,omitempty"`
+	RRType    RRType        `json:"rrtype,omitempty"`
+	TTL       int           `json:"ttl,omitempty"`
+	RData     []string      `json:"rdata"`
+	Profile   TCPoolProfile `json:"profile"`
+}
+
+// TCPoolProfile is the "tc:TCPool" profile of a Traffic Controller pool.
+type TCPoolProfile struct {
+	Context     string `json:"@context"`
+	Description string `json:"description"`
+	RunProbes   bool   `json:"runProbes"`
+	ActOnProbes bool   `json:"actOnProbes"`
+	// MaxToLB is the number of records to serve at once.
+	MaxToLB int `json:"maxToLB,omitempty"`
+	// FailureThreshold is the number of failing records before the backup record is served.
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	// Status is reported by UltraDNS, e.g. "OK" or "CRITICAL". It is ignored when sent.
+	Status       string              `json:"status,omitempty"`
+	RDataInfo    []TCPoolRDataInfo   `json:"rdataInfo"`
+	BackupRecord *TCPoolBackupRecord `json:"backupRecord,omitempty"`
+	Monitor      *TCPoolMonitor      `json:"monitor,omitempty"`
+}
+
+// TCPoolRDataInfo configures a single member of a Traffic Controller pool.
+type TCPoolRDataInfo struct {
+	// State is one of TCPoolStateNormal, TCPoolStateActive or TCPoolStateInactive.
+	State         string `json:"state"`
+	RunProbes     bool   `json:"runProbes"`
+	Priority      int    `json:"priority"`
+	FailoverDelay int    `json:"failoverDelay"`
+	Threshold     int    `json:"threshold"`
+	Weight        int    `json:"weight,omitempty"`
+	// AvailableToServe is reported by UltraDNS. It is ignored when sent.
+	AvailableToServe bool `json:"availableToServe,omitempty"`
+}
+
+// TCPoolBackupRecord is served when too many pool members fail.
+type TCPoolBackupRecord struct {
+	RData         string `json:"rdata"`
+	FailoverDelay int    `json:"failoverDelay"`
+}
+
+// TCPoolMonitor is the probe UltraDNS uses to check the pool members.
+type TCPoolMonitor struct {
+	Method          string `json:"method"`
+	URL             string `json:"url"`
+	TransmittedData string `json:"transmittedData,omitempty"`
+	SearchString    string `json:"searchString,omitempty"`
+}
+
+// memberIndex returns the position of the member with the given rdata, or -1.
+func (pool *TCPool) memberIndex(rdata string) int {
+	for i, member := range pool.RData {
+		if member == rdata {
+			return i
+		}
+	}
+	return -1
+}
+
+// addMemberPatch computes the patch that appends a member to the pool.
+func (pool *TCPool) addMemberPatch(rdata string, info TCPoolRDataInfo) (jsonpatch.Patch, error) {
+	if _, err := ParseRData(pool.RRType, rdata); err != nil {
+		return nil, err
+	}
+	if err := checkTCPoolState(info.State); err != nil {
+		return nil, err
+	}
+	if pool.memberIndex(rdata) >= 0 {
+		return nil, fmt.Errorf("%s is already a member of pool %s", rdata, pool.OwnerName)
+	}
+	// rdata and rdataInfo are parallel arrays, so both are appended at the same position. The patch only applies if
+	// the members did not change since the pool was fetched, so the position is still the end.
+	index := strconv.Itoa(len(pool.RData))
+	return jsonpatch.Patch{}.
+		Test(jsonpatch.Pointer("rdata"), append([]string{}, pool.RData...)).
+		Add(jsonpatch.Pointer("rdata", index), rdata).
+		Add(jsonpatch.Pointer("profile", "rdataInfo", index), info), nil
+}
+
+// removeMemberPatch computes the patch that removes a member from the pool.
+func (pool *TCPool) removeMemberPatch(rdata string) (jsonpatch.Patch, error) {
+	index, err := pool.requireMember(rdata)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.Patch{}.
+		Test(jsonpatch.Pointer("rdata", index), rdata).
+		Remove(jsonpatch.Pointer("rdata", index)).
+		Remove(jsonpatch.Pointer("profile", "rdataInfo", index)), nil
+}
+
+// setMemberStatePatch computes the patch that changes the state of a member.
+func (pool *TCPool) setMemberStatePatch(rdata string, state string) (jsonpatch.Patch, error) {
+	if err := checkTCPoolState(state); err != nil {
+		return nil, err
+	}
+	index, err := pool.requireMember(rdata)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.Patch{}.
+		Test(jsonpatch.Pointer("rdata", index), rdata).
+		Replace(jsonpatch.Pointer("profile", "rdataInfo", index, "state"), state), nil
+}
+
+// requireMember returns the index of the member with the given rdata as a JSON Pointer token. Patches addressing the
+// member by this index test that it still holds the rdata first, so they fail rather than change another member if
+// the pool changed since it was fetched.
+func (pool *TCPool) requireMember(rdata string) (string, error) {
+	index := pool.memberIndex(rdata)
+	if index < 0 {
+		return "", fmt.Errorf("%s is not a member of pool %s", rdata, pool.OwnerName)
+	}
+	if index >= len(pool.Profile.RDataInfo) {
+		return "", fmt.Errorf("pool %s has no rdataInfo for %s", pool.OwnerName, rdata)
+	}
+	return strconv.Itoa(index), nil
+}
+
+// checkTCPoolState returns an error for unknown member states.
+func checkTCPoolState(state string) error {
+	switch state {
+	case TCPoolStateNormal, TCPoolStateActive, TCPoolStateInactive:
+		return nil
+	default:
+		return fmt.Errorf("unknown pool member state '%s'", state)
+	}
+}
+
+// tcPoolList is a page of pools, i.e. an RRSetList of the pools of a zone.
+type tcPoolList struct {
+	ZoneName   string     `json:"zoneName"`
+	RRSets     []TCPool   `json:"rrSets"`
+	QueryInfo  QueryInfo  `json:"queryInfo"`
+	ResultInfo ResultInfo `json:"resultInfo"`
+}
+
+// TCPoolsService provides typed access to Traffic Controller pools. Its changes return the ID of their task, if any.
+type TCPoolsService struct {
+	apiConn *APIConnection
+}
+
+// TCPools returns the service for managing Traffic Controller pools over this connection.
+func (apiConn *APIConnection) TCPools() *TCPoolsService {
+	return &TCPoolsService{apiConn: apiConn}
+}
+
+// List returns every Traffic Controller pool in the zone.
+func (s *TCPoolsService) List(ctx context.Context, zone string) ([]TCPool, error) {
+	query := (&ListOptions{Q: "kind:TC_POOLS"}).values()
+	var pools []TCPool
+	err := s.apiConn.Paginate(rrsetsPath(zone), query).Walk(ctx, func(page *Page) error {
+		list := tcPoolList{}
+		if err := page.Decode(&list); err != nil {
+			return err
+		}
+		pools = append(pools, list.RRSets...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// Get fetches the pool of the given type at owner. owner may be relative to the zone.
+func (s *TCPoolsService) Get(ctx context.Context, zone string, rrtype RRType, owner string) (*TCPool, error) {
+	list := tcPoolList{}
+	if err := s.apiConn.GetJSONContext(ctx, RRSetPath(zone, rrtype, owner), &list); err != nil {
+		return nil, err
+	}
+	if len(list.RRSets) == 0 {
+		return nil, fmt.Errorf("no %s pool at %s: %w", rrtype, AbsoluteOwnerName(owner, zone), apierror.NotFound)
+	}
+	pool := &list.RRSets[0]
+	if pool.Profile.Context != TCPoolContext {
+		return nil, fmt.Errorf("%s record set at %s is not a Traffic Controller pool", rrtype, pool.OwnerName)
+	}
+	return pool, nil
+}
+
+// Create creates a new pool. Profile.Context defaults to TCPoolContext.
+func (s *TCPoolsService) Create(ctx context.Context, zone string, pool *TCPool) (string, error) {
+	return s.send(ctx, "POST", zone, pool)
+}
+
+// Replace replaces an existing pool with the given one. Profile.Context defaults to TCPoolContext.
+func (s *TCPoolsService) Replace(ctx context.Context, zone string, pool *TCPool) (string, error) {
+	return s.send(ctx, "PUT", zone, pool)
+}
+
+// send validates the pool and sends it to its path with the given method.
+func (s *TCPoolsService) send(ctx context.Context, method string, zone string, pool *TCPool) (string, error) {
+	if pool.Profile.Context == "" {
+		pool.Profile.Context = TCPoolContext
+	}
+	if len(pool.RData) != len(pool.Profile.RDataInfo) {
+		return "", fmt.Errorf("pool has %d records but %d rdataInfo entries", len(pool.RData), len(pool.Profile.RDataInfo))
+	}
+	if err := (&RRSet{RRType: pool.RRType, RData: pool.RData}).Validate(); err != nil {
+		return "", err
+	}
+	body, err := encodeJSON(pool)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.apiConn.request(ctx, method, RRSetPath(zone, pool.RRType, pool.OwnerName), "application/json", body)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
+
+// Delete deletes the pool of the given type at owner.
+func (s *TCPoolsService) Delete(ctx context.Context, zone string, rrtype RRType, owner string) (string, error) {
+	return s.apiConn.RRSets().Delete(ctx, zone, rrtype, owner)
+}
+
+// AddPoolMember fetches the pool and appends a member with the given rdata, e.g. an IP address, configured by info.
+func (s *TCPoolsService) AddPoolMember(ctx context.Context, zone string, rrtype RRType, owner string, rdata string, info TCPoolRDataInfo) (string, error) {
+	return s.patch(ctx, zone, rrtype, owner, func(pool *TCPool) (jsonpatch.Patch, error) {
+		return pool.addMemberPatch(rdata, info)
+	})
+}
+
+// RemovePoolMember fetches the pool and removes the member with the given rdata.
+func (s *TCPoolsService) RemovePoolMember(ctx context.Context, zone string, rrtype RRType, owner string, rdata string) (string, error) {
+	return s.patch(ctx, zone, rrtype, owner, func(pool *TCPool) (jsonpatch.Patch, error) {
+		return pool.removeMemberPatch(rdata)
+	})
+}
+
+// SetMemberState fetches the pool and sets the state of the member with the given rdata, e.g. to
+// TCPoolStateInactive to take it out of rotation.
+func (s *TCPoolsService) SetMemberState(ctx context.Context, zone string, rrtype RRType, owner string, rdata string, state string) (string, error) {
+	return s.patch(ctx, zone, rrtype, owner, func(pool *TCPool) (jsonpatch.Patch, error) {
+		return pool.setMemberStatePatch(rdata, state)
+	})
+}
+
+// patch fetches the current pool, computes a patch against it and submits the patch.
+func (s *TCPoolsService) patch(ctx context.Context, zone string, rrtype RRType, owner string, compute func(pool *TCPool) (jsonpatch.Patch, error)) (string, error) {
+	pool, err := s.Get(ctx, zone, rrtype, owner)
+	if err != nil {
+		return "", err
+	}
+	// The type is needed to validate new members; fall back to the requested one if the response omits it.
+	if pool.RRType == 0 {
+		pool.RRType = rrtype
+	}
+	patch, err := compute(pool)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.RRSets().JSONPatch(ctx, zone, rrtype, owner, patch)
+}
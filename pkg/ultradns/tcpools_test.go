@@ -0,0 +1,164 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+	"github.com/stretchr/testify/assert"
+)
+
+const tcPoolJSON = `{"zoneName":"example.com.","rrSets":[{"ownerName":"pool.example.com.","rrtype":"A (1)","ttl":300,"rdata":["192.0.2.1","192.0.2.2"],"profile":{"@context":"http://schemas.ultradns.com/TCPool.jsonschema","description":"pool","runProbes":true,"actOnProbes":true,"maxToLB":1,"status":"OK","rdataInfo":[{"state":"NORMAL","runProbes":true,"priority":1,"failoverDelay":0,"threshold":1,"availableToServe":true},{"state":"NORMAL","runProbes":true,"priority":2,"failoverDelay":0,"threshold":1,"availableToServe":true}],"backupRecord":{"rdata":"192.0.2.99","failoverDelay":0}}}]}`
+
+// tcPoolServer serves tcPoolJSON and records the body of the JSON Patch request it receives.
+func tcPoolServer(t *testing.T, patch *string) (*httptest.Server, *APIConnection) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/example.com./rrsets/A/pool.example.com.", r.URL.Path)
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(tcPoolJSON))
+		case "PATCH":
+			assert.Equal(t, "application/json-patch+json", r.Header.Get("Content-Type"))
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			*patch = string(body)
+			w.Write([]byte(`{"message":"Successful"}`))
+		}
+	})
+	return server, apiConn
+}
+
+func TestTCPoolsGet(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	pool, err := apiConn.TCPools().Get(context.Background(), "example.com.", RRTypeA, "pool")
+	assert.NoError(t, err)
+	assert.Equal(t, RRTypeA, pool.RRType)
+	assert.Equal(t, 2, pool.Profile.RDataInfo[1].Priority)
+	assert.Equal(t, "192.0.2.99", pool.Profile.BackupRecord.RData)
+}
+
+func TestTCPoolsAddPoolMember(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	info := TCPoolRDataInfo{State: TCPoolStateNormal, RunProbes: true, Priority: 3, Threshold: 1}
+	_, err := apiConn.TCPools().AddPoolMember(context.Background(), "example.com.", RRTypeA, "pool", "192.0.2.3", info)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op":"test","path":"/rdata","value":["192.0.2.1","192.0.2.2"]},
+		{"op":"add","path":"/rdata/2","value":"192.0.2.3"},
+		{"op":"add","path":"/profile/rdataInfo/2","value":{"state":"NORMAL","runProbes":true,"priority":3,"failoverDelay":0,"threshold":1}}
+	]`, patch)
+}
+
+func TestTCPoolsRemovePoolMember(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	_, err := apiConn.TCPools().RemovePoolMember(context.Background(), "example.com.", RRTypeA, "pool", "192.0.2.2")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op":"test","path":"/rdata/1","value":"192.0.2.2"},
+		{"op":"remove","path":"/rdata/1"},
+		{"op":"remove","path":"/profile/rdataInfo/1"}
+	]`, patch)
+}
+
+func TestTCPoolsSetMemberState(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	_, err := apiConn.TCPools().SetMemberState(context.Background(), "example.com.", RRTypeA, "pool", "192.0.2.2", TCPoolStateInactive)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op":"test","path":"/rdata/1","value":"192.0.2.2"},
+		{"op":"replace","path":"/profile/rdataInfo/1/state","value":"INACTIVE"}
+	]`, patch)
+}
+
+func TestTCPoolsPatchesFailIfPoolChanged(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	// The pool as changed by someone else after it was fetched: the first member was removed.
+	changed := []byte(`{"rdata":["192.0.2.2"],"profile":{"rdataInfo":[{"state":"NORMAL"}]}}`)
+	pools := apiConn.TCPools()
+	ctx := context.Background()
+	info := TCPoolRDataInfo{State: TCPoolStateNormal}
+	for _, change := range []func() (string, error){
+		func() (string, error) {
+			return pools.AddPoolMember(ctx, "example.com.", RRTypeA, "pool", "192.0.2.3", info)
+		},
+		func() (string, error) {
+			return pools.RemovePoolMember(ctx, "example.com.", RRTypeA, "pool", "192.0.2.2")
+		},
+		func() (string, error) {
+			return pools.SetMemberState(ctx, "example.com.", RRTypeA, "pool", "192.0.2.2", TCPoolStateInactive)
+		},
+	} {
+		_, err := change()
+		assert.NoError(t, err)
+		ops := jsonpatch.Patch{}
+		assert.NoError(t, json.Unmarshal([]byte(patch), &ops))
+		_, err = ops.Apply(changed)
+		assert.Error(t, err, patch)
+	}
+}
+
+func TestTCPoolsRejectsInvalidChanges(t *testing.T) {
+	var patch string
+	server, apiConn := tcPoolServer(t, &patch)
+	defer server.Close()
+
+	pools := apiConn.TCPools()
+	ctx := context.Background()
+	info := TCPoolRDataInfo{State: TCPoolStateNormal}
+	_, err := pools.AddPoolMember(ctx, "example.com.", RRTypeA, "pool", "192.0.2.1", info)
+	assert.Error(t, err, "duplicate member")
+	_, err = pools.AddPoolMember(ctx, "example.com.", RRTypeA, "pool", "2001:db8::1", info)
+	assert.Error(t, err, "wrong address family")
+	_, err = pools.AddPoolMember(ctx, "example.com.", RRTypeA, "pool", "192.0.2.3", TCPoolRDataInfo{State: "BOGUS"})
+	assert.Error(t, err)
+	_, err = pools.RemovePoolMember(ctx, "example.com.", RRTypeA, "pool", "192.0.2.3")
+	assert.Error(t, err, "not a member")
+	_, err = pools.SetMemberState(ctx, "example.com.", RRTypeA, "pool", "192.0.2.1", "OFF")
+	assert.Error(t, err)
+	assert.Equal(t, "", patch)
+}
+
+func TestTCPoolsCreateDefaultsContext(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		pool := TCPool{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&pool))
+		assert.Equal(t, TCPoolContext, pool.Profile.Context)
+		w.WriteHeader(201)
+	})
+	defer server.Close()
+
+	pool := &TCPool{
+		OwnerName: "pool",
+		RRType:    RRTypeA,
+		RData:     []string{"192.0.2.1"},
+		Profile: TCPoolProfile{
+			RDataInfo: []TCPoolRDataInfo{{State: TCPoolStateNormal, Priority: 1}},
+		},
+	}
+	_, err := apiConn.TCPools().Create(context.Background(), "example.com.", pool)
+	assert.NoError(t, err)
+
+	pool.RData = append(pool.RData, "192.0.2.2")
+	_, err = apiConn.TCPools().Create(context.Background(), "example.com.", pool)
+	assert.Error(t, err, "rdataInfo mismatch")
+}
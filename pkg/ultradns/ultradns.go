@@ -2,18 +2,103 @@
 package ultradns
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/simplifi/ultradns-go/internal/ultradns"
 )
 
+// AuthorizationError is returned when the RefreshToken was rejected and authorizing with the Username/Password failed
+// as well. Both errors are available on it.
+type AuthorizationError = ultradns.AuthorizationError
+
+// CredentialsProvider supplies the credentials used to request tokens. See APIOptions.Credentials.
+type CredentialsProvider = ultradns.CredentialsProvider
+
+// Credentials are a Username/Password, a RefreshToken, or both.
+type Credentials = ultradns.Credentials
+
+// CredentialsFunc adapts a function to a CredentialsProvider, e.g. to fetch credentials from a secret manager.
+type CredentialsFunc = ultradns.CredentialsFunc
+
+// ChainCredentials returns the credentials of the first provider that has any.
+type ChainCredentials = ultradns.ChainCredentials
+
+// EnvCredentials reads the credentials from the ULTRADNS_USERNAME, ULTRADNS_PASSWORD and ULTRADNS_REFRESH_TOKEN
+// environment variables.
+type EnvCredentials = ultradns.EnvCredentials
+
+// ProfileCredentials reads the credentials from a named profile of an INI-style file, by default the "default"
+// profile of ~/.ultradns/credentials.
+type ProfileCredentials = ultradns.ProfileCredentials
+
+// ErrNoCredentials is returned by a CredentialsProvider that has no credentials to offer.
+var ErrNoCredentials = ultradns.ErrNoCredentials
+
+// DefaultCredentials returns the environment variables, followed by the given profile of the credentials file, so
+// that programs can keep passwords out of their command line, and thereby out of the process list and shell history.
+// Pass it as APIOptions.Credentials to use it; connections never read the environment or the file on their own.
+func DefaultCredentials(profile string) CredentialsProvider {
+	return ultradns.DefaultCredentials(profile)
+}
+
+// TokenStore persists tokens so that several connections, possibly in different processes, share them instead of each
+// requesting their own. See APIOptions.TokenStore.
+type TokenStore = ultradns.TokenStore
+
+// Tokens are the tokens persisted by a TokenStore.
+type Tokens = ultradns.Tokens
+
+// FileTokenStore is a TokenStore keeping the tokens in a file that is only readable by its owner.
+type FileTokenStore = ultradns.FileTokenStore
+
+// MemoryTokenStore is a TokenStore keeping the tokens in memory.
+type MemoryTokenStore = ultradns.MemoryTokenStore
+
+// NewFileTokenStore returns a TokenStore keeping the tokens in the file at path, e.g. to share them between
+// short-lived processes such as cron jobs.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return ultradns.NewFileTokenStore(path)
+}
+
+// NewMemoryTokenStore returns a TokenStore keeping the tokens in memory, e.g. to share them between several
+// connections of one process.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return ultradns.NewMemoryTokenStore()
+}
+
 // APIConnection defines a connection to the UltraDNS API.
 type APIConnection struct {
 	Client        *http.Client
 	Authorization *ultradns.Authorization
 	BaseURL       string
+
+	// Retry controls retrying of failed requests. nil disables retries.
+	Retry *RetryPolicy
+
+	// Limiter paces all requests of the connection, including those to the token endpoint. nil does not limit
+	// requests.
+	Limiter *RateLimiter
+
+	// WaitForTasks makes the typed service methods wait for the tasks of changes that UltraDNS processes
+	// asynchronously, instead of returning the ID of the task as soon as the change was accepted.
+	WaitForTasks bool
+
+	// Logger receives a debug record for every request sent, including those to the token endpoint, and a warning for
+	// every retry. nil disables logging.
+	Logger Logger
+
+	// Debug adds the request headers and the request and response bodies to the records of the Logger, with
+	// credentials and tokens redacted.
+	Debug bool
+
+	// StrictJSON makes decoding a response fail if it has members the target type lacks.
+	StrictJSON bool
 }
 
 // APIOptions is an options struct for passing into NewAPIConnection()
@@ -28,11 +113,49 @@ type APIOptions struct {
 	// using the RefreshToken if available, and fall back to the Username/Password only if it is unavailable or rejected.
 	RefreshToken string
 
+	// Credentials supplies the Username/Password and RefreshToken when they are not set above. It is consulted
+	// whenever a token has to be requested, so rotated credentials are picked up. Set it to DefaultCredentials("") to
+	// read them from the environment or ~/.ultradns/credentials.
+	Credentials CredentialsProvider
+
 	// BaseURL is the first part of the API URL (default "https://api.ultradns.com")
 	BaseURL string
 
 	// Timeout is the underlying HTTP client timeout. Default is 5 seconds.
 	Timeout time.Duration
+
+	// Retry enables retrying of failed requests when set. See RetryPolicy for the defaults of its fields.
+	Retry *RetryPolicy
+
+	// RateLimit enables client side rate limiting when set. Independently of this, requests throttled by UltraDNS
+	// with 429 Too Many Requests are retried after the Retry-After the API asks for.
+	RateLimit *RateLimit
+
+	// WaitForTasks makes the typed service methods, e.g. Zones().Create, wait until asynchronous changes are done.
+	// Without it they return the ID of the task as soon as the change was accepted, to be passed to Tasks().Wait.
+	WaitForTasks bool
+
+	// Logger receives a debug record with the method, URL, status and latency of every request, token requests
+	// included, and a warning for every retry. *slog.Logger can be used directly; wrap a *log.Logger in StdLogger.
+	Logger Logger
+
+	// Debug also logs the request headers and the request and response bodies, with credentials and tokens redacted.
+	Debug bool
+
+	// StrictJSON rejects responses with members the target type lacks, e.g. to notice API changes in tests. It
+	// applies to the typed services and to Page.Decode as well. By default unknown members are ignored.
+	StrictJSON bool
+
+	// Transport is the http.RoundTripper that sends the requests. Default is http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Middleware wraps the Transport, in order, the first one being the outermost. It applies to all requests of the
+	// connection, including those to the token endpoint.
+	Middleware []Middleware
+
+	// TokenStore, when set, shares tokens with other connections using the same store. Stored tokens are used
+	// instead of authorizing as long as they are valid, and new tokens are saved to the store.
+	TokenStore TokenStore
 }
 
 func (options *APIOptions) setDefaults() {
@@ -49,15 +172,30 @@ func NewAPIConnection(options *APIOptions) *APIConnection {
 	options.setDefaults()
 
 	httpClient := &http.Client{
-		Timeout: options.Timeout,
+		Timeout:   options.Timeout,
+		Transport: chain(options.Transport, options.Middleware),
 	}
 	auth := ultradns.NewAuthorization(options.Username, options.Password)
+	auth.RefreshToken = options.RefreshToken
 	auth.BaseURL = options.BaseURL
+	auth.Store = options.TokenStore
+	auth.Credentials = options.Credentials
+
+	var limiter *RateLimiter
+	if options.RateLimit != nil {
+		limiter = NewRateLimiter(*options.RateLimit)
+	}
 
 	return &APIConnection{
 		Client:        httpClient,
 		Authorization: auth,
 		BaseURL:       options.BaseURL,
+		Retry:         options.Retry,
+		Limiter:       limiter,
+		WaitForTasks:  options.WaitForTasks,
+		Logger:        options.Logger,
+		Debug:         options.Debug,
+		StrictJSON:    options.StrictJSON,
 	}
 }
 
@@ -68,22 +206,12 @@ func NewAPIConnection(options *APIOptions) *APIConnection {
 // * Failing to connect to the API server
 // * When getting an HTTP status code of >= 400
 func (apiConn *APIConnection) Get(url string) (resp *http.Response, err error) {
-	if err = apiConn.Authorization.Authorize(apiConn.Client); err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("GET", apiConn.BaseURL+url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+apiConn.Authorization.AccessToken)
-
-	resp, err = apiConn.Client.Do(req)
-	if err == nil {
-		err = ultradns.GetError(resp)
-	}
+	return apiConn.GetContext(context.Background(), url)
+}
 
-	return resp, err
+// GetContext is like Get, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) GetContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "GET", url, "", nil)
 }
 
 // Post executes a POST request at the given url using the APIConnection's client and credentials.
@@ -95,20 +223,12 @@ func (apiConn *APIConnection) Get(url string) (resp *http.Response, err error) {
 // * Failing to connect to the API server
 // * When getting an HTTP status code of >= 400
 func (apiConn *APIConnection) Post(url string, body io.Reader) (resp *http.Response, err error) {
-	if err = apiConn.Authorization.Authorize(apiConn.Client); err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("POST", apiConn.BaseURL+url, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+apiConn.Authorization.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err = apiConn.Client.Do(req)
-	if err == nil {
-		err = ultradns.GetError(resp)
-	}
-	return resp, err
+	return apiConn.PostContext(context.Background(), url, body)
+}
+
+// PostContext is like Post, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) PostContext(ctx context.Context, url string, body io.Reader) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "POST", url, "application/json", body)
 }
 
 // Put executes a PUT request at the given url using the APIConnection's client and credentials.
@@ -120,20 +240,12 @@ func (apiConn *APIConnection) Post(url string, body io.Reader) (resp *http.Respo
 // * Failing to connect to the API server
 // * When getting an HTTP status code of >= 400
 func (apiConn *APIConnection) Put(url string, body io.Reader) (resp *http.Response, err error) {
-	if err = apiConn.Authorization.Authorize(apiConn.Client); err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("PUT", apiConn.BaseURL+url, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+apiConn.Authorization.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err = apiConn.Client.Do(req)
-	if err == nil {
-		err = ultradns.GetError(resp)
-	}
-	return resp, err
+	return apiConn.PutContext(context.Background(), url, body)
+}
+
+// PutContext is like Put, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) PutContext(ctx context.Context, url string, body io.Reader) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "PUT", url, "application/json", body)
 }
 
 // Patch executes a PATCH request at the given url using the APIConnection's client and credentials.
@@ -145,20 +257,12 @@ func (apiConn *APIConnection) Put(url string, body io.Reader) (resp *http.Respon
 // * Failing to connect to the API server
 // * When getting an HTTP status code of >= 400
 func (apiConn *APIConnection) Patch(url string, body io.Reader) (resp *http.Response, err error) {
-	if err = apiConn.Authorization.Authorize(apiConn.Client); err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("PATCH", apiConn.BaseURL+url, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+apiConn.Authorization.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err = apiConn.Client.Do(req)
-	if err == nil {
-		err = ultradns.GetError(resp)
-	}
-	return resp, err
+	return apiConn.PatchContext(context.Background(), url, body)
+}
+
+// PatchContext is like Patch, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) PatchContext(ctx context.Context, url string, body io.Reader) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "PATCH", url, "application/json", body)
 }
 
 // JSONPatch executes a PATCH request at the given url using the APIConnection's client and credentials.
@@ -172,18 +276,219 @@ func (apiConn *APIConnection) Patch(url string, body io.Reader) (resp *http.Resp
 // * Failing to connect to the API server
 // * When getting an HTTP status code of >= 400
 func (apiConn *APIConnection) JSONPatch(url string, body io.Reader) (resp *http.Response, err error) {
-	if err = apiConn.Authorization.Authorize(apiConn.Client); err != nil {
+	return apiConn.JSONPatchContext(context.Background(), url, body)
+}
+
+// JSONPatchContext is like JSONPatch, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) JSONPatchContext(ctx context.Context, url string, body io.Reader) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "PATCH", url, "application/json-patch+json", body)
+}
+
+// Delete executes a DELETE request at the given url using the APIConnection's client and credentials.
+//
+// error will be non-nil when:
+// * encountering an error authorizing
+// * Failing to connect to the API server
+// * When getting an HTTP status code of >= 400
+func (apiConn *APIConnection) Delete(url string) (resp *http.Response, err error) {
+	return apiConn.DeleteContext(context.Background(), url)
+}
+
+// DeleteContext is like Delete, but both the authorization and the API request are bound to the given context.
+func (apiConn *APIConnection) DeleteContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	return apiConn.request(ctx, "DELETE", url, "", nil)
+}
+
+// NewRequest returns a request for the given path below the BaseURL, to be sent with Do. The Content-Type is set to
+// 'application/json' if there is a body; set the header on the returned request to send another type.
+func (apiConn *APIConnection) NewRequest(method string, path string, body io.Reader) (*http.Request, error) {
+	return apiConn.NewRequestContext(context.Background(), method, path, body)
+}
+
+// NewRequestContext is like NewRequest, but the request is bound to the given context.
+func (apiConn *APIConnection) NewRequestContext(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, apiConn.BaseURL+path, body)
+	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("PATCH", apiConn.BaseURL+url, body)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// Do sends a request, e.g. one built with NewRequest, the same way as the verb methods: it authorizes and sets the
+// Authorization header, honors the Limiter and the Retry policy, re-authorizes when the token is rejected, and
+// returns an error for HTTP status codes >= 400. The request's context covers all of it. The body is buffered, so
+// that it can be sent again on every attempt.
+func (apiConn *APIConnection) Do(req *http.Request) (resp *http.Response, err error) {
+	ctx := req.Context()
+	var payload []byte
+	if req.Body != nil {
+		payload, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy := apiConn.Retry.withDefaults()
+	throttled := 0
+	reauthorized := false
+	for attempt := 1; ; {
+		if err = apiConn.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err = apiConn.send(req, payload)
+
+		// The token was rejected although it had not expired yet, e.g. because it was revoked. send has discarded
+		// it, so replaying the request once re-authorizes and uses a new token.
+		if isAuthFailure(resp, err) && !reauthorized {
+			reauthorized = true
+			continue
+		}
+
+		// Throttled requests were not processed, so they are safe to retry for any method. They are counted
+		// separately from failures, and the limiter is paused so that concurrent requests back off as well.
+		if delay, ok := throttleDelay(resp, time.Now()); ok && throttled < apiConn.Limiter.maxThrottledRetries() {
+			throttled++
+			apiConn.logRetry("throttled", req, delay, err)
+			apiConn.Limiter.Pause(delay)
+			if sleepErr := sleep(ctx, delay); sleepErr != nil {
+				return nil, sleepErr
+			}
+			continue
+		}
+
+		if attempt >= policy.maxAttempts(req.Method) || !policy.shouldRetry(resp, err) {
+			return resp, err
+		}
+		delay := policy.backoff(attempt)
+		apiConn.logRetry("failed", req, delay, err)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return nil, sleepErr
+		}
+		attempt++
+	}
+}
+
+// request builds a request for the path and sends it with Do. contentType is only set when non-empty.
+func (apiConn *APIConnection) request(ctx context.Context, method string, url string, contentType string, body io.Reader) (resp *http.Response, err error) {
+	req, err := apiConn.NewRequestContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return apiConn.Do(req)
+}
+
+// send authorizes and then sends a single attempt of a request to the API, with the given payload as its body. A nil
+// payload sends no body.
+func (apiConn *APIConnection) send(template *http.Request, payload []byte) (resp *http.Response, err error) {
+	ctx := template.Context()
+	token, err := apiConn.Authorization.TokenContext(ctx, apiConn.tokenClient())
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", "Bearer "+apiConn.Authorization.AccessToken)
-	req.Header.Add("Content-Type", "application/json-patch+json")
+	req := template.Clone(ctx)
+	req.Body, req.ContentLength, req.GetBody = nil, 0, nil
+	if payload != nil {
+		req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	start := time.Now()
 	resp, err = apiConn.Client.Do(req)
+	latency := time.Since(start)
 	if err == nil {
-		err = ultradns.GetError(resp)
+		var respBody []byte
+		if apiConn.Logger != nil && apiConn.Debug {
+			respBody = readBody(resp)
+		}
+		apiConn.logAttempt(req, payload, resp, respBody, latency, nil)
+		if err = ultradns.GetError(resp); err != nil {
+			// GetError has already consumed the body.
+			resp.Body.Close()
+		}
+	} else {
+		apiConn.logAttempt(req, payload, nil, nil, latency, err)
+	}
+	if isAuthFailure(resp, err) {
+		apiConn.Authorization.Invalidate(token)
 	}
 	return resp, err
 }
+
+// tokenClient returns the client to request tokens with. Token requests wait on the Limiter like the requests to the
+// API, and are logged like them, with the credentials in the form and the tokens in the response redacted.
+func (apiConn *APIConnection) tokenClient() *http.Client {
+	if apiConn.Limiter == nil && apiConn.Logger == nil {
+		return apiConn.Client
+	}
+	client := *apiConn.Client
+	client.Transport = &tokenTransport{apiConn: apiConn, next: client.Transport}
+	return &client
+}
+
+// tokenTransport is the transport of the tokenClient.
+type tokenTransport struct {
+	apiConn *APIConnection
+	next    http.RoundTripper
+}
+
+// RoundTrip waits for the Limiter, then sends the token request and logs it.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.apiConn.Limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	var payload []byte
+	if t.apiConn.Logger != nil && t.apiConn.Debug && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			payload, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	latency := time.Since(start)
+	if err != nil {
+		t.apiConn.logAttempt(req, payload, nil, nil, latency, err)
+		return nil, err
+	}
+	var respBody []byte
+	if t.apiConn.Logger != nil && t.apiConn.Debug {
+		respBody = readBody(resp)
+	}
+	t.apiConn.logAttempt(req, payload, resp, respBody, latency, nil)
+	return resp, nil
+}
+
+// isAuthFailure returns true if the API rejected the access token of a request, as opposed to the token endpoint
+// rejecting the credentials, which returns no response.
+func isAuthFailure(resp *http.Response, err error) bool {
+	if resp == nil || err == nil {
+		return false
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	errorResponse := ultradns.ErrorResponse{}
+	if !errors.As(err, &errorResponse) {
+		return false
+	}
+	switch errorResponse.ErrorCode() {
+	case 60001, 60004:
+		// 60001 is an invalid or expired token, 60004 a missing one.
+		return true
+	default:
+		return false
+	}
+}
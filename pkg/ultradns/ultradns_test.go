@@ -2,11 +2,16 @@ package ultradns
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -77,6 +82,28 @@ func stubbedServerAndAPIConn(t *testing.T) (*httptest.Server, *APIConnection) {
 	return server, apiConn
 }
 
+// Create a server from the given handler and a stubbed APIConnection pointing to it. The handler is only called for
+// requests that carry the valid access token.
+func handlerServerAndAPIConn(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *APIConnection) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validAccessToken {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"errorCode":60004,"errorMessage":"Authorization Header required"}`))
+			return
+		}
+		handler(w, r)
+	}))
+
+	auth := validAuthorization()
+	auth.BaseURL = server.URL
+
+	apiConn := NewAPIConnection(&APIOptions{})
+	apiConn.BaseURL = server.URL
+	apiConn.Authorization = auth
+
+	return server, apiConn
+}
+
 func TestClientGetSendsAuthToken(t *testing.T) {
 	server, apiConn := stubbedServerAndAPIConn(t)
 	defer server.Close()
@@ -116,3 +143,273 @@ func TestClientPostReturnsYepTrue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, `{"yep":true}`, string(bodyBytes))
 }
+
+func TestClientDeleteSendsDelete(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/zones/example.com.", r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	resp, err := apiConn.Delete("/zones/example.com.")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestNewRequestSetsURLAndContentType(t *testing.T) {
+	apiConn := NewAPIConnection(&APIOptions{BaseURL: "https://api.example.com/v2"})
+
+	req, err := apiConn.NewRequest("PUT", "/zones/example.com.", bytes.NewReader(correctPostBody))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/v2/zones/example.com.", req.URL.String())
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	req, err = apiConn.NewRequest("GET", "/zones", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Content-Type"))
+}
+
+func TestDoSendsCustomRequest(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+		assert.Equal(t, "yes", r.Header.Get("X-Custom"))
+		assert.Equal(t, "hello", string(body))
+		w.Write([]byte(`{"yep":true}`))
+	})
+	defer server.Close()
+
+	req, err := apiConn.NewRequest("POST", "/custom", bytes.NewReader([]byte("hello")))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Custom", "yes")
+
+	resp, err := apiConn.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	// The caller's request is left as is.
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestDoReplaysBodyAfterRejectedToken(t *testing.T) {
+	var tokenCalls int32
+	server := revokingServer(&tokenCalls, http.StatusUnauthorized, 60001)
+	defer server.Close()
+
+	apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL})
+	apiConn.Authorization.AccessToken = "revoked"
+	apiConn.Authorization.TokenExpires = time.Now().Unix() + 3600
+
+	req, err := apiConn.NewRequest("POST", "/post/endpoint", bytes.NewReader(correctPostBody))
+	assert.NoError(t, err)
+	resp, err := apiConn.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	// The server echoes the body, so it was sent again with the new token.
+	assert.Equal(t, correctPostBody, body)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+}
+
+// Create a server that never answers until the request's context is done, to exercise cancellation.
+func hangingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body must be drained for the server to notice the client going away.
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+}
+
+func TestClientGetContextHonorsDeadline(t *testing.T) {
+	server := hangingServer()
+	defer server.Close()
+
+	auth := validAuthorization()
+	auth.BaseURL = server.URL
+	apiConn := NewAPIConnection(&APIOptions{})
+	apiConn.BaseURL = server.URL
+	apiConn.Authorization = auth
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := apiConn.GetContext(ctx, "/foo")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+}
+
+func TestClientPostContextHonorsCancellation(t *testing.T) {
+	server, apiConn := stubbedServerAndAPIConn(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := apiConn.PostContext(ctx, "/post/endpoint", bytes.NewBuffer(correctPostBody))
+	assert.True(t, errors.Is(err, context.Canceled), "expected canceled, got %v", err)
+}
+
+func TestClientContextCancelsAuthorization(t *testing.T) {
+	server := hangingServer()
+	defer server.Close()
+
+	// No token yet, so the context must also cover the token request.
+	apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := apiConn.PutContext(ctx, "/foo", bytes.NewBuffer(correctPostBody))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+}
+
+func TestNewAPIConnectionSeedsRefreshToken(t *testing.T) {
+	apiConn := NewAPIConnection(&APIOptions{RefreshToken: validRefreshToken})
+	assert.Equal(t, validRefreshToken, apiConn.Authorization.RefreshToken)
+}
+
+func TestNewAPIConnectionIgnoresEnvironment(t *testing.T) {
+	saved := os.Getenv("ULTRADNS_USERNAME")
+	defer os.Setenv("ULTRADNS_USERNAME", saved)
+	os.Setenv("ULTRADNS_USERNAME", "from-env")
+
+	// Credentials are only read from the environment when asked to.
+	apiConn := NewAPIConnection(&APIOptions{})
+	assert.Nil(t, apiConn.Authorization.Credentials)
+	assert.Equal(t, "", apiConn.Authorization.Username)
+}
+
+// Test that concurrent requests on a connection without a token share a single token request.
+func TestConcurrentRequestsAuthorizeOnce(t *testing.T) {
+	var tokenCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authorization/token" {
+			atomic.AddInt32(&tokenCalls, 1)
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte(`{"accessToken":"` + validAccessToken + `","refreshToken":"` + validRefreshToken + `","expiresIn":"3600"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+validAccessToken {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"errorCode":60004,"errorMessage":"Authorization Header required"}`))
+			return
+		}
+		w.Write([]byte(`{"fooBar":"isFooBar"}`))
+	}))
+	defer server.Close()
+
+	apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := apiConn.Get("/foo")
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+}
+
+// revokingServer accepts only the most recently issued access token. Any other token is rejected with the given
+// status and errorCode, as if it had been revoked.
+func revokingServer(tokenCalls *int32, status int, errorCode int) *httptest.Server {
+	var mu sync.Mutex
+	current := ""
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/authorization/token" {
+			atomic.AddInt32(tokenCalls, 1)
+			current = fmt.Sprintf("%0x", rand.Int63())
+			w.Write([]byte(`{"accessToken":"` + current + `","refreshToken":"` + validRefreshToken + `","expiresIn":"3600"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+current {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"errorCode":%d,"errorMessage":"invalid_token"}`, errorCode)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+}
+
+// Test that a token rejected before its expiration is replaced and the request replayed.
+func TestRejectedTokenIsRenewedAndRequestReplayed(t *testing.T) {
+	for _, tc := range []struct {
+		status    int
+		errorCode int
+	}{
+		{401, 60001},
+		{400, 60004},
+		{401, 0},
+	} {
+		var tokenCalls int32
+		server := revokingServer(&tokenCalls, tc.status, tc.errorCode)
+
+		auth := validAuthorization()
+		auth.AccessToken = "revoked"
+		auth.BaseURL = server.URL
+		apiConn := NewAPIConnection(&APIOptions{})
+		apiConn.BaseURL = server.URL
+		apiConn.Authorization = auth
+
+		resp, err := apiConn.Post("/echo", bytes.NewBuffer(correctPostBody))
+		if assert.NoError(t, err, "status %d, code %d", tc.status, tc.errorCode) {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			// The body was replayed as well.
+			assert.Equal(t, correctPostBody, body)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+		server.Close()
+	}
+}
+
+// Test that a request is only replayed once if the new token is rejected as well.
+func TestRejectedTokenIsRenewedOnlyOnce(t *testing.T) {
+	var tokenCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authorization/token" {
+			atomic.AddInt32(&tokenCalls, 1)
+			w.Write([]byte(`{"accessToken":"` + validAccessToken + `","refreshToken":"` + validRefreshToken + `","expiresIn":"3600"}`))
+			return
+		}
+		w.WriteHeader(401)
+		w.Write([]byte(`{"errorCode":60001,"errorMessage":"invalid_token"}`))
+	}))
+	defer server.Close()
+
+	apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL})
+
+	_, err := apiConn.Get("/foo")
+	assert.EqualError(t, err, "60001: invalid_token")
+	// One token for the first attempt, and one for the replay.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokenCalls))
+}
+
+// Test that connections sharing a token store use the stored token instead of authorizing.
+func TestConnectionsShareTokenStore(t *testing.T) {
+	var tokenCalls int32
+	server := revokingServer(&tokenCalls, 401, 60001)
+	defer server.Close()
+	store := NewMemoryTokenStore()
+
+	for i := 0; i < 3; i++ {
+		apiConn := NewAPIConnection(&APIOptions{Username: validUsername, Password: validPassword, BaseURL: server.URL, TokenStore: store})
+		resp, err := apiConn.Post("/echo", bytes.NewBuffer(correctPostBody))
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+}
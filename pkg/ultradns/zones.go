@@ -0,0 +1,166 @@
+package ultradns
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Zone types accepted and returned by UltraDNS.
+const (
+	ZoneTypePrimary   = "PRIMARY"
+	ZoneTypeSecondary = "SECONDARY"
+	ZoneTypeAlias     = "ALIAS"
+)
+
+// ZoneProperties holds the properties UltraDNS reports for every zone, regardless of its type.
+type ZoneProperties struct {
+	Name                 string `json:"name"`
+	AccountName          string `json:"accountName,omitempty"`
+	Type                 string `json:"type"`
+	DNSSECStatus         string `json:"dnssecStatus,omitempty"`
+	Status               string `json:"status,omitempty"`
+	Owner                string `json:"owner,omitempty"`
+	ResourceRecordCount  int    `json:"resourceRecordCount,omitempty"`
+	LastModifiedDateTime string `json:"lastModifiedDateTime,omitempty"`
+}
+
+// LastModified parses LastModifiedDateTime. UltraDNS is not consistent about including seconds, so both forms are
+// accepted.
+func (p ZoneProperties) LastModified() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, p.LastModifiedDateTime)
+	if err != nil {
+		return time.Parse("2006-01-02T15:04Z07:00", p.LastModifiedDateTime)
+	}
+	return t, nil
+}
+
+// PrimaryZoneCreateInfo describes how a primary zone is created. Only used when creating a zone.
+type PrimaryZoneCreateInfo struct {
+	// CreateType is one of "NEW", "COPY", "TRANSFER" or "UPLOAD"
+	CreateType  string `json:"createType"`
+	ForceImport bool   `json:"forceImport"`
+	// OriginalZoneName is the zone to copy from when CreateType is "COPY"
+	OriginalZoneName string `json:"originalZoneName,omitempty"`
+}
+
+// Zone is a representation of an UltraDNS zone.
+type Zone struct {
+	Properties        ZoneProperties         `json:"properties"`
+	PrimaryCreateInfo *PrimaryZoneCreateInfo `json:"primaryCreateInfo,omitempty"`
+}
+
+// ZoneList is a single page of the zone listing.
+type ZoneList struct {
+	QueryInfo  QueryInfo  `json:"queryInfo"`
+	ResultInfo ResultInfo `json:"resultInfo"`
+	Zones      []Zone     `json:"zones"`
+
+	// CursorInfo is only set by the cursor based /v3/zones listing.
+	CursorInfo *CursorInfo `json:"cursorInfo,omitempty"`
+}
+
+// ZonesService provides typed access to the /zones endpoints. The methods changing a zone return the ID of the task
+// UltraDNS processes the change in, or "" if it was processed synchronously; see APIOptions.WaitForTasks.
+type ZonesService struct {
+	apiConn *APIConnection
+}
+
+// Zones returns the service for managing zones over this connection.
+func (apiConn *APIConnection) Zones() *ZonesService {
+	return &ZonesService{apiConn: apiConn}
+}
+
+// zonePath returns the API path of a single zone.
+func zonePath(name string) string {
+	return "/zones/" + url.PathEscape(name)
+}
+
+// List returns a single page of the zones visible to the account. opts may be nil.
+func (s *ZonesService) List(ctx context.Context, opts *ListOptions) (*ZoneList, error) {
+	list := &ZoneList{}
+	if err := s.apiConn.GetJSONContext(ctx, withQuery("/zones", opts.values()), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListAll returns every zone matching opts, following the cursor based /v3/zones listing. opts may be nil, and
+// opts.Offset is ignored.
+func (s *ZonesService) ListAll(ctx context.Context, opts *ListOptions) ([]Zone, error) {
+	query := opts.values()
+	query.Del("offset")
+
+	var zones []Zone
+	err := s.apiConn.PaginateCursor("/v3/zones", query).Walk(ctx, func(page *Page) error {
+		list := ZoneList{}
+		if err := page.Decode(&list); err != nil {
+			return err
+		}
+		zones = append(zones, list.Zones...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// Get fetches a single zone by name.
+func (s *ZonesService) Get(ctx context.Context, name string) (*Zone, error) {
+	zone := &Zone{}
+	if err := s.apiConn.GetJSONContext(ctx, zonePath(name), zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// Create creates the zone as described. For primary zones, PrimaryCreateInfo must be set.
+func (s *ZonesService) Create(ctx context.Context, zone *Zone) (string, error) {
+	body, err := encodeJSON(zone)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.apiConn.PostContext(ctx, "/zones", body)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
+
+// CreatePrimary creates a new, empty primary zone owned by the given account.
+func (s *ZonesService) CreatePrimary(ctx context.Context, name string, accountName string) (string, error) {
+	return s.Create(ctx, &Zone{
+		Properties: ZoneProperties{
+			Name:        name,
+			AccountName: accountName,
+			Type:        ZoneTypePrimary,
+		},
+		PrimaryCreateInfo: &PrimaryZoneCreateInfo{
+			CreateType:  "NEW",
+			ForceImport: true,
+		},
+	})
+}
+
+// Update replaces the zone's settings with the given zone. The zone is identified by zone.Properties.Name.
+func (s *ZonesService) Update(ctx context.Context, zone *Zone) (string, error) {
+	body, err := encodeJSON(zone)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.apiConn.PutContext(ctx, zonePath(zone.Properties.Name), body)
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
+
+// Delete deletes the zone and all of its records.
+func (s *ZonesService) Delete(ctx context.Context, name string) (string, error) {
+	resp, err := s.apiConn.DeleteContext(ctx, zonePath(name))
+	if err != nil {
+		return "", err
+	}
+	return s.apiConn.complete(ctx, resp)
+}
@@ -0,0 +1,108 @@
+package ultradns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/stretchr/testify/assert"
+)
+
+const zoneJSON = `{"properties":{"name":"example.com.","accountName":"acct","type":"PRIMARY","dnssecStatus":"UNSIGNED","status":"ACTIVE","owner":"good_user","resourceRecordCount":7,"lastModifiedDateTime":"2020-03-05T17:40Z"}}`
+
+func TestZonesList(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones", r.URL.Path)
+		assert.Equal(t, "name:example", r.URL.Query().Get("q"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"queryInfo":{"q":"name:example","sort":"NAME","reverse":false,"limit":10},"resultInfo":{"totalCount":1,"offset":0,"returnedCount":1},"zones":[` + zoneJSON + `]}`))
+	})
+	defer server.Close()
+
+	list, err := apiConn.Zones().List(context.Background(), &ListOptions{Q: "name:example", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.ResultInfo.TotalCount)
+	assert.Len(t, list.Zones, 1)
+	assert.Equal(t, "example.com.", list.Zones[0].Properties.Name)
+	assert.Equal(t, 7, list.Zones[0].Properties.ResourceRecordCount)
+}
+
+func TestZonesGet(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/example.com.", r.URL.Path)
+		w.Write([]byte(zoneJSON))
+	})
+	defer server.Close()
+
+	zone, err := apiConn.Zones().Get(context.Background(), "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, "UNSIGNED", zone.Properties.DNSSECStatus)
+	assert.Equal(t, "good_user", zone.Properties.Owner)
+	modified, err := zone.Properties.LastModified()
+	assert.NoError(t, err)
+	assert.Equal(t, 2020, modified.Year())
+}
+
+func TestZonesGetNotFound(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`{"errorCode":1801,"errorMessage":"Zone does not exist in the system."}`))
+	})
+	defer server.Close()
+
+	zone, err := apiConn.Zones().Get(context.Background(), "missing.com.")
+	assert.Nil(t, zone)
+	assert.EqualError(t, err, "1801: Zone does not exist in the system.")
+	assert.True(t, errors.Is(err, apierror.NotFound))
+
+	apiErr := &apierror.Error{}
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "GET", apiErr.Method)
+		assert.Equal(t, server.URL+"/zones/missing.com.", apiErr.URL)
+		assert.Equal(t, 404, apiErr.StatusCode)
+		assert.Equal(t, 1801, apiErr.Code)
+	}
+}
+
+func TestZonesCreatePrimary(t *testing.T) {
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/zones", r.URL.Path)
+		zone := Zone{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&zone))
+		assert.Equal(t, "example.com.", zone.Properties.Name)
+		assert.Equal(t, "acct", zone.Properties.AccountName)
+		assert.Equal(t, ZoneTypePrimary, zone.Properties.Type)
+		assert.Equal(t, "NEW", zone.PrimaryCreateInfo.CreateType)
+		w.WriteHeader(201)
+		w.Write([]byte(`{"message":"Successful"}`))
+	})
+	defer server.Close()
+
+	_, err := apiConn.Zones().CreatePrimary(context.Background(), "example.com.", "acct")
+	assert.NoError(t, err)
+}
+
+func TestZonesUpdateAndDelete(t *testing.T) {
+	var methods []string
+	server, apiConn := handlerServerAndAPIConn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/example.com.", r.URL.Path)
+		methods = append(methods, r.Method)
+		if r.Method == "DELETE" {
+			w.WriteHeader(204)
+			return
+		}
+		w.Write([]byte(`{"message":"Successful"}`))
+	})
+	defer server.Close()
+
+	zone := &Zone{Properties: ZoneProperties{Name: "example.com.", Type: ZoneTypePrimary}}
+	_, err := apiConn.Zones().Update(context.Background(), zone)
+	assert.NoError(t, err)
+	_, err = apiConn.Zones().Delete(context.Background(), "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PUT", "DELETE"}, methods)
+}
@@ -0,0 +1,121 @@
+package ultradnstest
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Fault describes how the Server misbehaves for matching requests, e.g. to answer with an error or delay the
+// response. Faults are checked before the request is authorized, so they apply to the token endpoint as well.
+type Fault struct {
+	// Method restricts the fault to requests with this method. "" matches any method.
+	Method string
+
+	// Path restricts the fault to requests whose path matches this pattern, as used by path.Match, e.g.
+	// "/zones/*/rrsets/*/*". "" matches any path.
+	Path string
+
+	// Times is how many matching requests the fault applies to. 0 applies it to all of them.
+	Times int
+
+	// Delay delays the response. Unless one of the fields below is set as well, the request is then processed as
+	// usual.
+	Delay time.Duration
+
+	// StatusCode, when set, answers the request with this status instead of processing it. The body is an error
+	// with ErrorCode and ErrorMessage in the shape of the real API, or Body if that is set.
+	StatusCode   int
+	ErrorCode    int
+	ErrorMessage string
+	Body         string
+
+	// Header is added to the response, e.g. a Retry-After header for 429 Too Many Requests.
+	Header http.Header
+
+	// Drop closes the connection without answering, so the client sees a transport error.
+	Drop bool
+
+	hits int
+}
+
+// Inject adds a fault. Faults are matched in the order they were added, and only the first matching one applies.
+func (s *Server) Inject(fault *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, fault)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// FailNext makes the next request to a path matching the pattern fail with the given status and error code, e.g.
+// FailNext("/zones/*", http.StatusInternalServerError, 0).
+func (s *Server) FailNext(pattern string, statusCode int, errorCode int) {
+	s.Inject(&Fault{Path: pattern, Times: 1, StatusCode: statusCode, ErrorCode: errorCode})
+}
+
+// Throttle makes the next n requests fail with 429 Too Many Requests, asking to retry after the given seconds.
+func (s *Server) Throttle(n int, retryAfter int) {
+	s.Inject(&Fault{
+		Times:        n,
+		StatusCode:   http.StatusTooManyRequests,
+		ErrorMessage: "Rate limit exceeded",
+		Header:       http.Header{"Retry-After": {strconv.Itoa(retryAfter)}},
+	})
+}
+
+// matchFault returns the first fault matching the request and counts the hit. Faults that were used up are removed.
+// The caller must hold the lock.
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, fault := range s.faults {
+		if fault.Method != "" && fault.Method != r.Method {
+			continue
+		}
+		if fault.Path != "" {
+			if matched, _ := path.Match(fault.Path, r.URL.Path); !matched {
+				continue
+			}
+		}
+		fault.hits++
+		if fault.Times > 0 && fault.hits >= fault.Times {
+			s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+		}
+		return fault
+	}
+	return nil
+}
+
+// inject applies the fault to the response. It returns false if the request should still be processed.
+func (fault *Fault) inject(w http.ResponseWriter) bool {
+	if fault.Delay > 0 {
+		time.Sleep(fault.Delay)
+	}
+	if fault.Drop {
+		// Aborts the handler and closes the connection without a response.
+		panic(http.ErrAbortHandler)
+	}
+	if fault.StatusCode == 0 {
+		return false
+	}
+	for key, values := range fault.Header {
+		w.Header()[key] = values
+	}
+	if fault.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fault.StatusCode)
+		w.Write([]byte(fault.Body))
+		return true
+	}
+	message := fault.ErrorMessage
+	if message == "" {
+		message = http.StatusText(fault.StatusCode)
+	}
+	writeError(w, fault.StatusCode, fault.ErrorCode, message)
+	return true
+}
@@ -0,0 +1,352 @@
+package ultradnstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/simplifi/ultradns-go/pkg/jsonpatch"
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// defaultTTL is the TTL of record sets created without one.
+const defaultTTL = 86400
+
+// poolContexts maps the pool kinds of the q parameter to the name of their profile schema.
+var poolContexts = map[string]string{
+	"TC_POOLS":  "TCPool",
+	"RD_POOLS":  "RDPool",
+	"SF_POOLS":  "SFPool",
+	"SB_POOLS":  "SBPool",
+	"SLB_POOLS": "SLBPool",
+	"DIR_POOLS": "DirPool",
+}
+
+// rrsetJSON is a record set as sent by the API, which reports the type along with its number, e.g. "A (1)".
+type rrsetJSON struct {
+	OwnerName string          `json:"ownerName"`
+	RRType    string          `json:"rrtype"`
+	TTL       int             `json:"ttl,omitempty"`
+	RData     []string        `json:"rdata"`
+	Profile   json.RawMessage `json:"profile,omitempty"`
+}
+
+// toJSON returns the record set as sent by the API.
+func toJSON(rrset *ultradns.RRSet) rrsetJSON {
+	return rrsetJSON{
+		OwnerName: rrset.OwnerName,
+		RRType:    fmt.Sprintf("%s (%d)", rrset.RRType, int(rrset.RRType)),
+		TTL:       rrset.TTL,
+		RData:     rrset.RData,
+		Profile:   rrset.Profile,
+	}
+}
+
+// rrsetKey returns the key of a record set in zone.rrsets. owner must be absolute.
+func rrsetKey(rrtype ultradns.RRType, owner string) string {
+	return fmt.Sprintf("%d %s", rrtype, strings.ToLower(owner))
+}
+
+// sortedRRSets returns the record sets of the zone ordered by owner and type.
+func (z *zone) sortedRRSets() []*ultradns.RRSet {
+	rrsets := make([]*ultradns.RRSet, 0, len(z.rrsets))
+	for _, rrset := range z.rrsets {
+		rrsets = append(rrsets, rrset)
+	}
+	sort.Slice(rrsets, func(i, j int) bool {
+		if rrsets[i].OwnerName != rrsets[j].OwnerName {
+			return rrsets[i].OwnerName < rrsets[j].OwnerName
+		}
+		return rrsets[i].RRType < rrsets[j].RRType
+	})
+	return rrsets
+}
+
+// PutRRSet creates or replaces a record set in an existing zone. The owner may be relative to the zone.
+func (s *Server) PutRRSet(zoneName string, rrset ultradns.RRSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	z, ok := s.zones[zoneKey(zoneName)]
+	if !ok {
+		return fmt.Errorf("zone %s does not exist", zoneName)
+	}
+	if err := rrset.Validate(); err != nil {
+		return err
+	}
+	rrset.OwnerName = ultradns.AbsoluteOwnerName(rrset.OwnerName, z.properties.Name)
+	if rrset.TTL == 0 {
+		rrset.TTL = defaultTTL
+	}
+	z.rrsets[rrsetKey(rrset.RRType, rrset.OwnerName)] = &rrset
+	z.touch()
+	return nil
+}
+
+// PutTCPool creates or replaces a Traffic Controller pool in an existing zone. Profile.Context defaults to
+// ultradns.TCPoolContext.
+func (s *Server) PutTCPool(zoneName string, pool ultradns.TCPool) error {
+	if pool.Profile.Context == "" {
+		pool.Profile.Context = ultradns.TCPoolContext
+	}
+	profile, err := json.Marshal(pool.Profile)
+	if err != nil {
+		return err
+	}
+	return s.PutRRSet(zoneName, ultradns.RRSet{
+		OwnerName: pool.OwnerName,
+		RRType:    pool.RRType,
+		TTL:       pool.TTL,
+		RData:     pool.RData,
+		Profile:   profile,
+	})
+}
+
+// RRSet returns the record set of the given type at owner, if it exists. The owner may be relative to the zone.
+func (s *Server) RRSet(zoneName string, rrtype ultradns.RRType, owner string) (ultradns.RRSet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	z, ok := s.zones[zoneKey(zoneName)]
+	if !ok {
+		return ultradns.RRSet{}, false
+	}
+	rrset, ok := z.rrsets[rrsetKey(rrtype, ultradns.AbsoluteOwnerName(owner, z.properties.Name))]
+	if !ok {
+		return ultradns.RRSet{}, false
+	}
+	return *rrset, true
+}
+
+// RRSets returns the record sets of the zone, ordered by owner and type.
+func (s *Server) RRSets(zoneName string) []ultradns.RRSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	z, ok := s.zones[zoneKey(zoneName)]
+	if !ok {
+		return nil
+	}
+	var rrsets []ultradns.RRSet
+	for _, rrset := range z.sortedRRSets() {
+		rrsets = append(rrsets, *rrset)
+	}
+	return rrsets
+}
+
+// routeRRSets dispatches the requests below /zones/{zone}/rrsets.
+func (s *Server) routeRRSets(w http.ResponseWriter, r *http.Request, zoneName string, segments []string, body []byte, async bool) {
+	z, ok := s.requireZone(w, zoneName)
+	if !ok {
+		return
+	}
+	var rrtype ultradns.RRType
+	if len(segments) > 0 {
+		var err error
+		if rrtype, err = ultradns.ParseRRType(segments[0]); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidInput, err.Error())
+			return
+		}
+	}
+	var owner string
+	if len(segments) > 1 {
+		owner = ultradns.AbsoluteOwnerName(segments[1], z.properties.Name)
+	}
+
+	switch {
+	case len(segments) < 2 && r.Method == "GET":
+		s.listRRSets(w, r, z, rrtype)
+	case len(segments) == 2 && r.Method == "GET":
+		s.getRRSet(w, r, z, rrtype, owner)
+	case len(segments) == 2 && (r.Method == "POST" || r.Method == "PUT"):
+		s.putRRSet(w, r.Method, z, rrtype, owner, body, async)
+	case len(segments) == 2 && r.Method == "PATCH":
+		s.patchRRSet(w, r, z, rrtype, owner, body, async)
+	case len(segments) == 2 && r.Method == "DELETE":
+		if _, ok := s.requireRRSet(w, z, rrtype, owner); !ok {
+			return
+		}
+		delete(z.rrsets, rrsetKey(rrtype, owner))
+		z.touch()
+		s.writeSuccess(w, http.StatusNoContent, async)
+	default:
+		writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+	}
+}
+
+// listRRSets answers a listing of the zone's record sets, optionally of a single type. The q parameter supports the
+// owner and kind terms, e.g. "kind:TC_POOLS".
+func (s *Server) listRRSets(w http.ResponseWriter, r *http.Request, z *zone, rrtype ultradns.RRType) {
+	query := r.URL.Query()
+	terms := parseQ(query.Get("q"))
+	var rrsets []rrsetJSON
+	for _, rrset := range z.sortedRRSets() {
+		if rrtype != 0 && rrset.RRType != rrtype {
+			continue
+		}
+		if owner, ok := terms["owner"]; ok && !strings.Contains(strings.ToLower(rrset.OwnerName), strings.ToLower(owner)) {
+			continue
+		}
+		if kind, ok := terms["kind"]; ok && !matchesKind(rrset, strings.ToUpper(kind)) {
+			continue
+		}
+		rrsets = append(rrsets, toJSON(rrset))
+	}
+	offset, limit, ok := pageBounds(w, query, "offset")
+	if !ok {
+		return
+	}
+	page, resultInfo := pageOf(len(rrsets), offset, limit)
+	writeRRSetList(w, z, rrsets[page[0]:page[1]], queryInfo(query, limit), resultInfo)
+}
+
+// matchesKind reports whether the record set is of a kind of the q parameter: RECORDS, POOLS or a type of pool.
+func matchesKind(rrset *ultradns.RRSet, kind string) bool {
+	switch kind {
+	case "ALL":
+		return true
+	case "RECORDS":
+		return len(rrset.Profile) == 0
+	case "POOLS":
+		return len(rrset.Profile) > 0
+	}
+	schema, ok := poolContexts[kind]
+	if !ok || len(rrset.Profile) == 0 {
+		return false
+	}
+	profile := struct {
+		Context string `json:"@context"`
+	}{}
+	json.Unmarshal(rrset.Profile, &profile)
+	return strings.HasSuffix(profile.Context, "/"+schema+".jsonschema")
+}
+
+// getRRSet answers the request for a single record set, which the API sends as a list of one.
+func (s *Server) getRRSet(w http.ResponseWriter, r *http.Request, z *zone, rrtype ultradns.RRType, owner string) {
+	rrset, ok := s.requireRRSet(w, z, rrtype, owner)
+	if !ok {
+		return
+	}
+	resultInfo := ultradns.ResultInfo{TotalCount: 1, ReturnedCount: 1}
+	writeRRSetList(w, z, []rrsetJSON{toJSON(rrset)}, queryInfo(r.URL.Query(), defaultLimit), resultInfo)
+}
+
+// writeRRSetList writes a page of record sets.
+func writeRRSetList(w http.ResponseWriter, z *zone, rrsets []rrsetJSON, queryInfo ultradns.QueryInfo, resultInfo ultradns.ResultInfo) {
+	if rrsets == nil {
+		rrsets = []rrsetJSON{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"zoneName":   z.properties.Name,
+		"rrSets":     rrsets,
+		"queryInfo":  queryInfo,
+		"resultInfo": resultInfo,
+	})
+}
+
+// putRRSet creates (POST) or replaces (PUT) a record set.
+func (s *Server) putRRSet(w http.ResponseWriter, method string, z *zone, rrtype ultradns.RRType, owner string, body []byte, async bool) {
+	rrset := &ultradns.RRSet{}
+	if !decodeBody(w, body, rrset) {
+		return
+	}
+	_, exists := z.rrsets[rrsetKey(rrtype, owner)]
+	if method == "POST" && exists {
+		writeError(w, http.StatusBadRequest, CodeRRSetExists,
+			fmt.Sprintf("Resource Record of type %d with these attributes already exists in the system.", int(rrtype)))
+		return
+	}
+	if method == "PUT" && !exists {
+		s.requireRRSet(w, z, rrtype, owner)
+		return
+	}
+	if !s.store(w, z, rrtype, owner, rrset) {
+		return
+	}
+	statusCode := http.StatusOK
+	if method == "POST" {
+		statusCode = http.StatusCreated
+	}
+	s.writeSuccess(w, statusCode, async)
+}
+
+// patchRRSet applies a JSON Patch or, for other content types, a partial record set to an existing record set.
+func (s *Server) patchRRSet(w http.ResponseWriter, r *http.Request, z *zone, rrtype ultradns.RRType, owner string, body []byte, async bool) {
+	current, ok := s.requireRRSet(w, z, rrtype, owner)
+	if !ok {
+		return
+	}
+	patched := *current
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json-patch+json") {
+		patch := jsonpatch.Patch{}
+		if !decodeBody(w, body, &patch) {
+			return
+		}
+		doc, err := json.Marshal(current)
+		if err == nil {
+			doc, err = patch.Apply(doc)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidInput, "Invalid JSON Patch: "+err.Error())
+			return
+		}
+		patched = ultradns.RRSet{}
+		if !decodeBody(w, doc, &patched) {
+			return
+		}
+	} else {
+		partial := ultradns.RRSet{}
+		if !decodeBody(w, body, &partial) {
+			return
+		}
+		if partial.TTL != 0 {
+			patched.TTL = partial.TTL
+		}
+		if partial.RData != nil {
+			patched.RData = partial.RData
+		}
+		if partial.Profile != nil {
+			patched.Profile = partial.Profile
+		}
+	}
+	if !s.store(w, z, rrtype, owner, &patched) {
+		return
+	}
+	s.writeSuccess(w, http.StatusOK, async)
+}
+
+// store validates the record set and saves it under the owner and type of its path, which it must not contradict.
+func (s *Server) store(w http.ResponseWriter, z *zone, rrtype ultradns.RRType, owner string, rrset *ultradns.RRSet) bool {
+	if rrset.RRType != 0 && rrset.RRType != rrtype {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, fmt.Sprintf("Record type %s does not match the path.", rrset.RRType))
+		return false
+	}
+	if rrset.OwnerName != "" && !strings.EqualFold(ultradns.AbsoluteOwnerName(rrset.OwnerName, z.properties.Name), owner) {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, fmt.Sprintf("Owner name %s does not match the path.", rrset.OwnerName))
+		return false
+	}
+	rrset.RRType, rrset.OwnerName = rrtype, owner
+	if rrset.TTL == 0 {
+		rrset.TTL = defaultTTL
+	}
+	if len(rrset.RData) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, "At least one rdata is required.")
+		return false
+	}
+	if err := rrset.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, err.Error())
+		return false
+	}
+	z.rrsets[rrsetKey(rrtype, owner)] = rrset
+	z.touch()
+	return true
+}
+
+// requireRRSet returns the record set, answering with an error if it doesn't exist.
+func (s *Server) requireRRSet(w http.ResponseWriter, z *zone, rrtype ultradns.RRType, owner string) (*ultradns.RRSet, bool) {
+	rrset, ok := z.rrsets[rrsetKey(rrtype, owner)]
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeRRSetNotFound,
+			"Cannot find resource record data for the input zone, record type and owner combination.")
+	}
+	return rrset, ok
+}
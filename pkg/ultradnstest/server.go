@@ -0,0 +1,368 @@
+// Package ultradnstest provides an in-process fake of the UltraDNS REST API, to test code using package ultradns
+// without network access:
+//
+//	server := ultradnstest.NewServer()
+//	defer server.Close()
+//	server.AddZone("example.com.")
+//
+//	apiConn := server.Connection()
+//	_, err := apiConn.RRSets().Create(ctx, "example.com.", &ultradns.RRSet{...})
+//
+// The fake keeps zones, record sets (including Traffic Controller pools) and tasks in memory, issues and checks
+// tokens, and answers with the JSON shapes and error codes of the real API. Faults can be injected to test error
+// handling, e.g. throttling or failing requests.
+package ultradnstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// Credentials of the user every Server accepts. Further users can be added with AddUser.
+const (
+	Username = "ultradnstest"
+	Password = "ultradnstest-password"
+)
+
+// AccountName is the account that zones belong to unless they are created with another one.
+const AccountName = "ultradnstest"
+
+// Error codes the fake answers with. They are the codes the real API uses for the same conditions, except for
+// CodeInvalidInput, which the fake uses for every request it cannot parse or validate.
+const (
+	CodeZoneNotFound  = 1801
+	CodeZoneExists    = 1802
+	CodeRRSetExists   = 2111
+	CodeInvalidInput  = 55001
+	CodeRRSetNotFound = 56001
+	CodeInvalidToken  = 60001
+	CodeMissingToken  = 60004
+	CodeDataNotFound  = 70002
+)
+
+// Request is a request received by the Server, as returned by Requests.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a fake UltraDNS API listening on a local port. Its exported fields must be set before requests are sent.
+type Server struct {
+	*httptest.Server
+
+	// TokenTTL is how long issued access tokens are valid. Default is one hour.
+	TokenTTL time.Duration
+
+	// Async makes changes to zones and record sets complete asynchronously, answering with 202 Accepted and an
+	// X-Task-Id header like the real API does for large changes. The change is visible immediately, but its task
+	// only completes after TaskPolls polls.
+	Async bool
+
+	// TaskPolls is the number of times an asynchronous task is reported as IN_PROCESS before it completes.
+	TaskPolls int
+
+	mu            sync.Mutex
+	users         map[string]string
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]bool
+	zones         map[string]*zone
+	tasks         map[string]*task
+	taskOrder     []string
+	faults        []*Fault
+	requests      []Request
+}
+
+// NewServer starts a Server without any zones that accepts the Username and Password. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		TokenTTL:      time.Hour,
+		users:         map[string]string{Username: Password},
+		accessTokens:  map[string]time.Time{},
+		refreshTokens: map[string]bool{},
+		zones:         map[string]*zone{},
+		tasks:         map[string]*task{},
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Options returns APIOptions for a connection to the server with the Username and Password.
+func (s *Server) Options() *ultradns.APIOptions {
+	return &ultradns.APIOptions{
+		Username: Username,
+		Password: Password,
+		BaseURL:  s.URL,
+	}
+}
+
+// Connection returns a connection to the server, created from Options.
+func (s *Server) Connection() *ultradns.APIConnection {
+	return ultradns.NewAPIConnection(s.Options())
+}
+
+// AddUser adds a user that can authorize with the given password.
+func (s *Server) AddUser(username string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// RevokeTokens invalidates all access and refresh tokens issued so far, as if they had been revoked, so that clients
+// have to authorize with their password again.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = map[string]time.Time{}
+	s.refreshTokens = map[string]bool{}
+}
+
+// Requests returns the requests received so far, in order, including those to the token endpoint.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, "Unable to read request body.")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	fault := s.matchFault(r)
+	s.mu.Unlock()
+
+	if fault != nil && fault.inject(w) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/authorization/token" {
+		s.token(w, r, body)
+		return
+	}
+	if !s.authorized(w, r) {
+		return
+	}
+	s.route(w, r, body, true)
+}
+
+// route dispatches an authorized request. async is false for the operations of a batch, which never start tasks of
+// their own. The caller must hold the lock.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, body []byte, async bool) {
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, err.Error())
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case len(segments) == 1 && segments[0] == "status" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Good"})
+	case len(segments) == 1 && segments[0] == "batch" && r.Method == "POST":
+		s.batch(w, r, body, async && query.Get("async") == "true")
+	case len(segments) >= 1 && segments[0] == "tasks":
+		s.routeTasks(w, r, segments[1:])
+	case len(segments) == 2 && segments[0] == "v3" && segments[1] == "zones" && r.Method == "GET":
+		s.listZones(w, query, true)
+	case len(segments) >= 1 && segments[0] == "zones":
+		s.routeZones(w, r, segments[1:], body, async && s.Async)
+	default:
+		writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+	}
+}
+
+// routeZones dispatches the requests below /zones.
+func (s *Server) routeZones(w http.ResponseWriter, r *http.Request, segments []string, body []byte, async bool) {
+	query := r.URL.Query()
+	switch {
+	case len(segments) == 0 && r.Method == "GET":
+		s.listZones(w, query, false)
+	case len(segments) == 0 && r.Method == "POST":
+		s.createZone(w, body, async)
+	case len(segments) == 1 && r.Method == "GET":
+		s.getZone(w, segments[0])
+	case len(segments) == 1 && (r.Method == "PUT" || r.Method == "PATCH"):
+		s.updateZone(w, segments[0], body, async)
+	case len(segments) == 1 && r.Method == "DELETE":
+		s.deleteZone(w, segments[0], async)
+	case len(segments) >= 2 && segments[1] == "rrsets":
+		s.routeRRSets(w, r, segments[0], segments[2:], body, async)
+	default:
+		writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+	}
+}
+
+// pathSegments splits the unescaped segments of the URL's path.
+func pathSegments(u *url.URL) ([]string, error) {
+	var segments []string
+	for _, escaped := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		if escaped == "" {
+			continue
+		}
+		segment, err := url.PathUnescape(escaped)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// token handles the token endpoint. The caller must hold the lock.
+func (s *Server) token(w http.ResponseWriter, r *http.Request, body []byte) {
+	form, err := url.ParseQuery(string(body))
+	if r.Method != "POST" || err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, "Invalid token request.")
+		return
+	}
+
+	switch form.Get("grant_type") {
+	case "password":
+		password, ok := s.users[form.Get("username")]
+		if !ok || password != form.Get("password") {
+			writeGrantError(w, "invalid_grant:Invalid username & password combination.")
+			return
+		}
+	case "refresh_token":
+		if !s.refreshTokens[form.Get("refresh_token")] {
+			writeGrantError(w, "invalid_grant:Token not found, expired or invalid.")
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"errorCode":         CodeInvalidToken,
+			"errorMessage":      "unsupported_grant_type",
+			"error":             "unsupported_grant_type",
+			"error_description": fmt.Sprintf("%d: unsupported_grant_type", CodeInvalidToken),
+		})
+		return
+	}
+
+	accessToken, refreshToken := randomToken(), randomToken()
+	s.accessTokens[accessToken] = time.Now().Add(s.TokenTTL)
+	s.refreshTokens[refreshToken] = true
+	expiresIn := fmt.Sprintf("%d", int64(s.TokenTTL/time.Second))
+	writeJSON(w, http.StatusOK, map[string]string{
+		"tokenType":     "Bearer",
+		"accessToken":   accessToken,
+		"refreshToken":  refreshToken,
+		"expiresIn":     expiresIn,
+		"token_type":    "Bearer",
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    expiresIn,
+	})
+}
+
+// authorized checks the request's access token, answering with an error if it is missing or invalid. The caller must
+// hold the lock.
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		writeError(w, http.StatusUnauthorized, CodeMissingToken, "Authorization Header required")
+		return false
+	}
+	expires, ok := s.accessTokens[strings.TrimPrefix(header, "Bearer ")]
+	if !ok || time.Now().After(expires) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"errorCode":    CodeInvalidToken,
+			"errorMessage": "invalid_token:Access token expired or invalid.",
+			"error":        "invalid_token",
+		})
+		return false
+	}
+	return true
+}
+
+// randomToken returns a new, random token.
+func randomToken() string {
+	return fmt.Sprintf("%016x%016x", rand.Int63(), rand.Int63())
+}
+
+// writeJSON writes v as the JSON body of a response with the given status code.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// writeError writes an error response in the shape of the real API.
+func writeError(w http.ResponseWriter, statusCode int, code int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{"errorCode": code, "errorMessage": message})
+}
+
+// writeErrors writes a response reporting several errors at once, as a JSON array.
+func writeErrors(w http.ResponseWriter, statusCode int, code int, messages []string) {
+	errors := make([]map[string]interface{}, len(messages))
+	for i, message := range messages {
+		errors[i] = map[string]interface{}{"errorCode": code, "errorMessage": message}
+	}
+	writeJSON(w, statusCode, errors)
+}
+
+// writeGrantError writes the error of a rejected token request.
+func writeGrantError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"errorCode":         CodeInvalidToken,
+		"errorMessage":      message,
+		"error":             "invalid_grant",
+		"error_description": fmt.Sprintf("%d: %s", CodeInvalidToken, message),
+	})
+}
+
+// writeSuccess writes the response to a change, or the 202 Accepted of its task if it is processed asynchronously.
+// The caller must hold the lock.
+func (s *Server) writeSuccess(w http.ResponseWriter, statusCode int, async bool) {
+	if async {
+		task := s.newTask(nil)
+		w.Header().Set("X-Task-Id", task.TaskID)
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "Pending"})
+		return
+	}
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return
+	}
+	writeJSON(w, statusCode, map[string]string{"message": "Successful"})
+}
+
+// decodeBody decodes a JSON request body into v, answering with an error if it is invalid.
+func decodeBody(w http.ResponseWriter, body []byte, v interface{}) bool {
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, "Invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,361 @@
+package ultradnstest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simplifi/ultradns-go/pkg/apierror"
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusRequiresToken(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	status := struct {
+		Message string `json:"message"`
+	}{}
+	assert.NoError(t, server.Connection().GetJSON("/status", &status))
+	assert.Equal(t, "Good", status.Message)
+}
+
+func TestWrongPasswordIsRejected(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	options := server.Options()
+	options.Password = "wrong"
+	_, err := ultradns.NewAPIConnection(options).Get("/status")
+	assert.True(t, errors.Is(err, apierror.Unauthorized))
+	assert.Contains(t, err.Error(), "invalid_grant")
+}
+
+func TestZoneLifecycle(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	zones := server.Connection().Zones()
+	ctx := context.Background()
+
+	_, err := zones.CreatePrimary(ctx, "example.com", "my-account")
+	assert.NoError(t, err)
+	_, err = zones.CreatePrimary(ctx, "example.com.", "my-account")
+	assert.True(t, errors.Is(err, apierror.AlreadyExists))
+
+	zone, err := zones.Get(ctx, "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com.", zone.Properties.Name)
+	assert.Equal(t, "my-account", zone.Properties.AccountName)
+	assert.Equal(t, ultradns.ZoneTypePrimary, zone.Properties.Type)
+	_, err = zone.Properties.LastModified()
+	assert.NoError(t, err)
+
+	_, err = zones.Delete(ctx, "example.com.")
+	assert.NoError(t, err)
+	_, err = zones.Get(ctx, "example.com.")
+	assert.True(t, errors.Is(err, apierror.NotFound))
+	assert.Equal(t, CodeZoneNotFound, apiErrorCode(err))
+}
+
+func TestInvalidZoneReportsAllErrors(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	_, err := server.Connection().Zones().Create(context.Background(), &ultradns.Zone{})
+	apiErr := &apierror.Error{}
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, apierror.Validation, apiErr.Kind)
+		assert.Len(t, apiErr.Details, 2)
+	}
+}
+
+func TestListAllZonesFollowsCursor(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	for _, name := range []string{"c.com.", "a.com.", "b.com.", "other.net."} {
+		server.AddZone(name)
+	}
+
+	zones, err := server.Connection().Zones().ListAll(context.Background(), &ultradns.ListOptions{Q: "name:com", Limit: 2})
+	assert.NoError(t, err)
+	var names []string
+	for _, zone := range zones {
+		names = append(names, zone.Properties.Name)
+	}
+	assert.Equal(t, []string{"a.com.", "b.com.", "c.com."}, names)
+
+	list, err := server.Connection().Zones().List(context.Background(), &ultradns.ListOptions{Offset: 1, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, ultradns.ResultInfo{TotalCount: 4, Offset: 1, ReturnedCount: 2}, list.ResultInfo)
+}
+
+func TestRRSetLifecycle(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+	rrsets := server.Connection().RRSets()
+	ctx := context.Background()
+
+	www := &ultradns.RRSet{OwnerName: "www", RRType: ultradns.RRTypeA, TTL: 300, RData: []string{"192.0.2.1"}}
+	_, err := rrsets.Create(ctx, "example.com.", www)
+	assert.NoError(t, err)
+	_, err = rrsets.Create(ctx, "example.com.", www)
+	assert.True(t, errors.Is(err, apierror.AlreadyExists))
+
+	current, err := rrsets.Get(ctx, "example.com.", ultradns.RRTypeA, "www")
+	assert.NoError(t, err)
+	assert.Equal(t, "www.example.com.", current.OwnerName)
+	assert.Equal(t, ultradns.RRTypeA, current.RRType)
+
+	desired := *current
+	desired.RData = []string{"192.0.2.1", "192.0.2.2"}
+	_, err = rrsets.Update(ctx, "example.com.", current, &desired)
+	assert.NoError(t, err)
+	stored, ok := server.RRSet("example.com.", ultradns.RRTypeA, "www")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, stored.RData)
+	assert.Equal(t, 300, stored.TTL)
+
+	_, err = rrsets.Patch(ctx, "example.com.", &ultradns.RRSet{OwnerName: "www", RRType: ultradns.RRTypeA, TTL: 60})
+	assert.NoError(t, err)
+	stored, _ = server.RRSet("example.com.", ultradns.RRTypeA, "www")
+	assert.Equal(t, 60, stored.TTL)
+	assert.Len(t, stored.RData, 2)
+
+	zone, _ := server.Zone("example.com.")
+	assert.Equal(t, 2, zone.Properties.ResourceRecordCount)
+
+	_, err = rrsets.Delete(ctx, "example.com.", ultradns.RRTypeA, "www")
+	assert.NoError(t, err)
+	_, err = rrsets.Get(ctx, "example.com.", ultradns.RRTypeA, "www")
+	assert.True(t, errors.Is(err, apierror.NotFound))
+	assert.Equal(t, CodeRRSetNotFound, apiErrorCode(err))
+}
+
+func TestInvalidRRSetIsRejected(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+
+	err := server.Connection().PostJSON("/zones/example.com./rrsets/A/www", map[string]interface{}{
+		"ttl":   300,
+		"rdata": []string{"not an address"},
+	}, nil)
+	assert.True(t, errors.Is(err, apierror.Validation))
+	assert.Empty(t, server.RRSets("example.com."))
+}
+
+func TestTCPools(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+	assert.NoError(t, server.PutRRSet("example.com.", ultradns.RRSet{OwnerName: "plain", RRType: ultradns.RRTypeA, RData: []string{"192.0.2.9"}}))
+	assert.NoError(t, server.PutTCPool("example.com.", ultradns.TCPool{
+		OwnerName: "pool",
+		RRType:    ultradns.RRTypeA,
+		RData:     []string{"192.0.2.1"},
+		Profile: ultradns.TCPoolProfile{
+			RDataInfo: []ultradns.TCPoolRDataInfo{{State: ultradns.TCPoolStateNormal, Priority: 1, Threshold: 1}},
+		},
+	}))
+	pools := server.Connection().TCPools()
+	ctx := context.Background()
+
+	all, err := pools.List(ctx, "example.com.")
+	assert.NoError(t, err)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "pool.example.com.", all[0].OwnerName)
+	}
+
+	info := ultradns.TCPoolRDataInfo{State: ultradns.TCPoolStateNormal, Priority: 2, Threshold: 1}
+	_, err = pools.AddPoolMember(ctx, "example.com.", ultradns.RRTypeA, "pool", "192.0.2.2", info)
+	assert.NoError(t, err)
+	_, err = pools.SetMemberState(ctx, "example.com.", ultradns.RRTypeA, "pool", "192.0.2.1", ultradns.TCPoolStateInactive)
+	assert.NoError(t, err)
+
+	pool, err := pools.Get(ctx, "example.com.", ultradns.RRTypeA, "pool")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, pool.RData)
+	assert.Equal(t, ultradns.TCPoolStateInactive, pool.Profile.RDataInfo[0].State)
+	assert.Equal(t, 2, pool.Profile.RDataInfo[1].Priority)
+}
+
+func TestTypedListsDecodeStrictly(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+	assert.NoError(t, server.PutTCPool("example.com.", ultradns.TCPool{
+		OwnerName: "pool",
+		RRType:    ultradns.RRTypeA,
+		RData:     []string{"192.0.2.1"},
+		Profile: ultradns.TCPoolProfile{
+			RDataInfo: []ultradns.TCPoolRDataInfo{{State: ultradns.TCPoolStateNormal, Priority: 1, Threshold: 1}},
+		},
+	}))
+	options := server.Options()
+	options.StrictJSON = true
+	apiConn := ultradns.NewAPIConnection(options)
+	ctx := context.Background()
+
+	_, err := apiConn.Zones().ListAll(ctx, nil)
+	assert.NoError(t, err)
+	_, err = apiConn.Zones().List(ctx, nil)
+	assert.NoError(t, err)
+	_, err = apiConn.RRSets().ListAll(ctx, "example.com.", nil)
+	assert.NoError(t, err)
+	_, err = apiConn.TCPools().List(ctx, "example.com.")
+	assert.NoError(t, err)
+	_, err = apiConn.TCPools().Get(ctx, "example.com.", ultradns.RRTypeA, "pool")
+	assert.NoError(t, err)
+}
+
+func TestAsyncChangesCompleteAsTasks(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Async = true
+	server.TaskPolls = 2
+	apiConn := server.Connection()
+	ctx := context.Background()
+
+	resp, err := apiConn.Post("/zones", strings.NewReader(`{"properties":{"name":"example.com.","type":"PRIMARY"}}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	taskID := ultradns.TaskID(resp)
+	assert.NotEmpty(t, taskID)
+
+	tasks := apiConn.Tasks()
+	tasks.PollInterval = time.Millisecond
+	task, err := tasks.Wait(ctx, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, ultradns.TaskComplete, task.Code)
+	var polls int
+	for _, req := range server.Requests() {
+		if req.Method == "GET" && req.Path == "/tasks/"+taskID {
+			polls++
+		}
+	}
+	assert.Equal(t, 3, polls)
+
+	list, err := tasks.List(ctx, &ultradns.ListOptions{Q: "status:COMPLETE"})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Tasks, 1) {
+		assert.Equal(t, taskID, list.Tasks[0].TaskID)
+	}
+	_, ok := server.Zone("example.com.")
+	assert.True(t, ok)
+}
+
+func TestFailedTask(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	id := server.AddTask(ultradns.Task{Code: ultradns.TaskFailed, Message: "Zone transfer failed"})
+
+	_, err := server.Connection().Tasks().Wait(context.Background(), id)
+	taskErr := &ultradns.TaskError{}
+	if assert.True(t, errors.As(err, &taskErr)) {
+		assert.Equal(t, "Zone transfer failed", taskErr.Task.Message)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+	ctx := context.Background()
+
+	for _, async := range []bool{false, true} {
+		batch := server.Connection().NewBatch().
+			CreateRRSet("example.com.", &ultradns.RRSet{OwnerName: "www", RRType: ultradns.RRTypeA, RData: []string{"192.0.2.1"}}).
+			DeleteRRSet("example.com.", ultradns.RRTypeA, "missing")
+		var results []ultradns.BatchResult
+		var err error
+		if async {
+			results, err = batch.SendAsync(ctx)
+		} else {
+			results, err = batch.Send(ctx)
+		}
+		assert.NoError(t, err)
+		if assert.Len(t, results, 2) {
+			if async {
+				// The record set was created by the first batch already.
+				assert.True(t, errors.Is(results[0].Err, apierror.AlreadyExists))
+			} else {
+				assert.NoError(t, results[0].Err)
+			}
+			assert.True(t, errors.Is(results[1].Err, apierror.NotFound))
+		}
+	}
+	_, ok := server.RRSet("example.com.", ultradns.RRTypeA, "www")
+	assert.True(t, ok)
+}
+
+func TestThrottleIsRetried(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	apiConn := server.Connection()
+	assert.NoError(t, apiConn.GetJSON("/status", nil))
+
+	server.Throttle(2, 0)
+	assert.NoError(t, apiConn.GetJSON("/status", nil))
+	assert.Len(t, server.Requests(), 5)
+}
+
+func TestFailNextIsRetried(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddZone("example.com.")
+	options := server.Options()
+	options.Retry = &ultradns.RetryPolicy{BaseBackoff: time.Millisecond}
+	apiConn := ultradns.NewAPIConnection(options)
+
+	server.FailNext("/zones/*", http.StatusServiceUnavailable, 0)
+	_, err := apiConn.Zones().Get(context.Background(), "example.com.")
+	assert.NoError(t, err)
+
+	server.FailNext("/zones/*", http.StatusBadRequest, CodeInvalidInput)
+	_, err = apiConn.Zones().Get(context.Background(), "example.com.")
+	assert.True(t, errors.Is(err, apierror.Validation))
+}
+
+func TestDroppedConnection(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	apiConn := server.Connection()
+	server.Inject(&Fault{Path: "/status", Drop: true})
+
+	err := apiConn.GetJSON("/status", nil)
+	assert.Error(t, err)
+	assert.Equal(t, apierror.Unknown, apierror.KindOf(err))
+
+	server.ClearFaults()
+	assert.NoError(t, apiConn.GetJSON("/status", nil))
+}
+
+func TestRevokedTokensAreRenewed(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	apiConn := server.Connection()
+	assert.NoError(t, apiConn.GetJSON("/status", nil))
+
+	server.RevokeTokens()
+	assert.NoError(t, apiConn.GetJSON("/status", nil))
+}
+
+// apiErrorCode returns the UltraDNS error code of err, or 0.
+func apiErrorCode(err error) int {
+	apiErr := &apierror.Error{}
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,184 @@
+package ultradnstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// task is an asynchronous task held by the Server.
+type task struct {
+	ultradns.Task
+
+	// polls counts the requests for the task's state.
+	polls int
+
+	// result is the body of /tasks/{id}/result, or nil if the task has none.
+	result json.RawMessage
+}
+
+// newTask starts a task that completes after TaskPolls polls. The caller must hold the lock.
+func (s *Server) newTask(result json.RawMessage) *task {
+	t := &task{
+		Task:   ultradns.Task{TaskID: randomToken(), Code: ultradns.TaskPending, Message: "Pending"},
+		result: result,
+	}
+	if result != nil {
+		t.ResultURI = s.URL + "/tasks/" + t.TaskID + "/result"
+	}
+	s.tasks[t.TaskID] = t
+	s.taskOrder = append(s.taskOrder, t.TaskID)
+	t.advance(s.TaskPolls)
+	return t
+}
+
+// advance moves the task on to IN_PROCESS and, once it has been polled polls times, to COMPLETE. Tasks that already
+// completed or failed keep their state.
+func (t *task) advance(polls int) {
+	if t.Done() {
+		return
+	}
+	if t.polls >= polls {
+		t.Code, t.Message = ultradns.TaskComplete, "Complete"
+	} else {
+		t.Code, t.Message = ultradns.TaskInProcess, "In process"
+	}
+}
+
+// AddTask adds a task in the given state, e.g. a failed one. A task without an ID gets a random one, which is
+// returned.
+func (s *Server) AddTask(state ultradns.Task) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.TaskID == "" {
+		state.TaskID = randomToken()
+	}
+	if _, exists := s.tasks[state.TaskID]; !exists {
+		s.taskOrder = append(s.taskOrder, state.TaskID)
+	}
+	s.tasks[state.TaskID] = &task{Task: state}
+	return state.TaskID
+}
+
+// routeTasks dispatches the requests below /tasks.
+func (s *Server) routeTasks(w http.ResponseWriter, r *http.Request, segments []string) {
+	if len(segments) == 0 {
+		if r.Method != "GET" {
+			writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+			return
+		}
+		s.listTasks(w, r)
+		return
+	}
+
+	t, ok := s.tasks[segments[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+		return
+	}
+	switch {
+	case len(segments) == 1 && r.Method == "GET":
+		t.advance(s.TaskPolls)
+		t.polls++
+		writeJSON(w, http.StatusOK, t.Task)
+	case len(segments) == 1 && r.Method == "DELETE":
+		delete(s.tasks, t.TaskID)
+		for i, id := range s.taskOrder {
+			if id == t.TaskID {
+				s.taskOrder = append(s.taskOrder[:i:i], s.taskOrder[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 2 && segments[1] == "result" && r.Method == "GET":
+		if t.Code != ultradns.TaskComplete {
+			writeError(w, http.StatusBadRequest, CodeInvalidInput, fmt.Sprintf("Task %s is not complete.", t.TaskID))
+			return
+		}
+		if t.result == nil {
+			writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(t.result)
+	default:
+		writeError(w, http.StatusNotFound, CodeDataNotFound, "Data not found.")
+	}
+}
+
+// listTasks answers the task listing. The q parameter supports the status term, e.g. "status:ERROR".
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status, filtered := parseQ(query.Get("q"))["status"]
+	tasks := []ultradns.Task{}
+	for _, id := range s.taskOrder {
+		t := s.tasks[id]
+		if filtered && !strings.EqualFold(string(t.Code), status) {
+			continue
+		}
+		tasks = append(tasks, t.Task)
+	}
+	offset, limit, ok := pageBounds(w, query, "offset")
+	if !ok {
+		return
+	}
+	page, resultInfo := pageOf(len(tasks), offset, limit)
+	writeJSON(w, http.StatusOK, ultradns.TaskList{
+		QueryInfo:  queryInfo(query, limit),
+		ResultInfo: resultInfo,
+		Tasks:      tasks[page[0]:page[1]],
+	})
+}
+
+// batchResult is the outcome of a single operation of a batch.
+type batchResult struct {
+	Code int             `json:"code"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// batch processes the operations of a batch one after another, as if they had been sent on their own, and answers
+// with the status and body of each. With async, the results are the result of a task instead.
+func (s *Server) batch(w http.ResponseWriter, r *http.Request, body []byte, async bool) {
+	operations := []struct {
+		Method string          `json:"method"`
+		URI    string          `json:"uri"`
+		Body   json.RawMessage `json:"body"`
+	}{}
+	if !decodeBody(w, body, &operations) {
+		return
+	}
+
+	results := make([]batchResult, len(operations))
+	for i, operation := range operations {
+		req := httptest.NewRequest(operation.Method, operation.URI, bytes.NewReader(operation.Body))
+		if operation.Method == "PATCH" && bytes.HasPrefix(bytes.TrimSpace(operation.Body), []byte("[")) {
+			req.Header.Set("Content-Type", "application/json-patch+json")
+		} else if len(operation.Body) > 0 {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		recorder := httptest.NewRecorder()
+		s.route(recorder, req, operation.Body, false)
+		results[i] = batchResult{Code: recorder.Code}
+		if recorder.Body.Len() > 0 {
+			results[i].Body = recorder.Body.Bytes()
+		}
+	}
+
+	if !async {
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+	result, err := json.Marshal(results)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t := s.newTask(result)
+	w.Header().Set("X-Task-Id", t.TaskID)
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Pending"})
+}
@@ -0,0 +1,298 @@
+package ultradnstest
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simplifi/ultradns-go/pkg/ultradns"
+)
+
+// defaultLimit is the page size of listings without a limit, as with the real API.
+const defaultLimit = 100
+
+// zone is a zone held by the Server.
+type zone struct {
+	properties ultradns.ZoneProperties
+	rrsets     map[string]*ultradns.RRSet
+}
+
+// zoneKey returns the key of the zone in Server.zones: the lower case name with a trailing dot.
+func zoneKey(name string) string {
+	return strings.ToLower(absoluteZoneName(name))
+}
+
+// absoluteZoneName returns the name with a trailing dot.
+func absoluteZoneName(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+// toZone returns the zone as sent by the API.
+func (z *zone) toZone() ultradns.Zone {
+	properties := z.properties
+	for _, rrset := range z.rrsets {
+		properties.ResourceRecordCount += len(rrset.RData)
+	}
+	return ultradns.Zone{Properties: properties}
+}
+
+// touch updates the modification time of the zone.
+func (z *zone) touch() {
+	z.properties.LastModifiedDateTime = time.Now().UTC().Format("2006-01-02T15:04Z")
+}
+
+// newZone returns an empty, active zone.
+func newZone(properties ultradns.ZoneProperties) *zone {
+	properties.Name = absoluteZoneName(properties.Name)
+	if properties.AccountName == "" {
+		properties.AccountName = AccountName
+	}
+	if properties.Type == "" {
+		properties.Type = ultradns.ZoneTypePrimary
+	}
+	properties.Status = "ACTIVE"
+	properties.DNSSECStatus = "UNSIGNED"
+	properties.ResourceRecordCount = 0
+	z := &zone{properties: properties, rrsets: map[string]*ultradns.RRSet{}}
+	z.touch()
+	return z
+}
+
+// AddZone adds an empty primary zone to the account, replacing any zone of the same name.
+func (s *Server) AddZone(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones[zoneKey(name)] = newZone(ultradns.ZoneProperties{Name: name})
+}
+
+// Zone returns the zone of the given name, if it exists.
+func (s *Server) Zone(name string) (ultradns.Zone, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	z, ok := s.zones[zoneKey(name)]
+	if !ok {
+		return ultradns.Zone{}, false
+	}
+	return z.toZone(), true
+}
+
+// Zones returns all zones, ordered by name.
+func (s *Server) Zones() []ultradns.Zone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedZones()
+}
+
+// sortedZones returns all zones ordered by name. The caller must hold the lock.
+func (s *Server) sortedZones() []ultradns.Zone {
+	zones := make([]ultradns.Zone, 0, len(s.zones))
+	for _, z := range s.zones {
+		zones = append(zones, z.toZone())
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Properties.Name < zones[j].Properties.Name
+	})
+	return zones
+}
+
+// listZones answers a zone listing, offset based or, for /v3/zones, cursor based. The q parameter supports the
+// name, zone_type and account_name terms.
+func (s *Server) listZones(w http.ResponseWriter, query url.Values, cursor bool) {
+	var zones []ultradns.Zone
+	terms := parseQ(query.Get("q"))
+	for _, z := range s.sortedZones() {
+		if name, ok := terms["name"]; ok && !strings.Contains(strings.ToLower(z.Properties.Name), strings.ToLower(name)) {
+			continue
+		}
+		if zoneType, ok := terms["zone_type"]; ok && !strings.EqualFold(z.Properties.Type, zoneType) {
+			continue
+		}
+		if account, ok := terms["account_name"]; ok && z.Properties.AccountName != account {
+			continue
+		}
+		zones = append(zones, z)
+	}
+	if query.Get("reverse") == "true" {
+		for i, j := 0, len(zones)-1; i < j; i, j = i+1, j-1 {
+			zones[i], zones[j] = zones[j], zones[i]
+		}
+	}
+
+	offsetParam := "offset"
+	if cursor {
+		offsetParam = "cursor"
+	}
+	offset, limit, ok := pageBounds(w, query, offsetParam)
+	if !ok {
+		return
+	}
+	page, resultInfo := pageOf(len(zones), offset, limit)
+	body := map[string]interface{}{
+		"queryInfo":  queryInfo(query, limit),
+		"resultInfo": resultInfo,
+		"zones":      zones[page[0]:page[1]],
+	}
+	if cursor {
+		cursorInfo := map[string]string{}
+		if page[1] < len(zones) {
+			cursorInfo["next"] = strconv.Itoa(page[1])
+		}
+		if offset > 0 {
+			cursorInfo["previous"] = strconv.Itoa(maxInt(offset-limit, 0))
+		}
+		body["cursorInfo"] = cursorInfo
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// getZone answers the request for a single zone.
+func (s *Server) getZone(w http.ResponseWriter, name string) {
+	z, ok := s.requireZone(w, name)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, z.toZone())
+}
+
+// createZone creates a zone. Primary zones created as a COPY get the record sets of the original zone.
+func (s *Server) createZone(w http.ResponseWriter, body []byte, async bool) {
+	request := ultradns.Zone{}
+	if !decodeBody(w, body, &request) {
+		return
+	}
+	var problems []string
+	if request.Properties.Name == "" {
+		problems = append(problems, "Zone name is required.")
+	}
+	switch request.Properties.Type {
+	case ultradns.ZoneTypePrimary, ultradns.ZoneTypeSecondary, ultradns.ZoneTypeAlias:
+	default:
+		problems = append(problems, "Zone type must be one of PRIMARY, SECONDARY or ALIAS.")
+	}
+	if len(problems) > 0 {
+		writeErrors(w, http.StatusBadRequest, CodeInvalidInput, problems)
+		return
+	}
+	if _, exists := s.zones[zoneKey(request.Properties.Name)]; exists {
+		writeError(w, http.StatusBadRequest, CodeZoneExists, "Zone already exists in the system.")
+		return
+	}
+
+	z := newZone(request.Properties)
+	if info := request.PrimaryCreateInfo; info != nil && info.CreateType == "COPY" {
+		original, ok := s.requireZone(w, info.OriginalZoneName)
+		if !ok {
+			return
+		}
+		for _, rrset := range original.rrsets {
+			copied := *rrset
+			copied.OwnerName = ultradns.AbsoluteOwnerName(ultradns.RelativeOwnerName(rrset.OwnerName, original.properties.Name), z.properties.Name)
+			copied.RData = append([]string(nil), rrset.RData...)
+			z.rrsets[rrsetKey(copied.RRType, copied.OwnerName)] = &copied
+		}
+	}
+	s.zones[zoneKey(z.properties.Name)] = z
+	s.writeSuccess(w, http.StatusCreated, async)
+}
+
+// updateZone changes the account or type of a zone.
+func (s *Server) updateZone(w http.ResponseWriter, name string, body []byte, async bool) {
+	z, ok := s.requireZone(w, name)
+	if !ok {
+		return
+	}
+	request := ultradns.Zone{}
+	if !decodeBody(w, body, &request) {
+		return
+	}
+	if request.Properties.AccountName != "" {
+		z.properties.AccountName = request.Properties.AccountName
+	}
+	if request.Properties.Type != "" {
+		z.properties.Type = request.Properties.Type
+	}
+	z.touch()
+	s.writeSuccess(w, http.StatusOK, async)
+}
+
+// deleteZone deletes a zone with all of its record sets.
+func (s *Server) deleteZone(w http.ResponseWriter, name string, async bool) {
+	if _, ok := s.requireZone(w, name); !ok {
+		return
+	}
+	delete(s.zones, zoneKey(name))
+	s.writeSuccess(w, http.StatusNoContent, async)
+}
+
+// requireZone returns the zone of the given name, answering with an error if it doesn't exist.
+func (s *Server) requireZone(w http.ResponseWriter, name string) (*zone, bool) {
+	z, ok := s.zones[zoneKey(name)]
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeZoneNotFound, "Zone does not exist in the system.")
+	}
+	return z, ok
+}
+
+// parseQ parses the q parameter of a listing, e.g. "name:example zone_type:PRIMARY", into its terms.
+func parseQ(q string) map[string]string {
+	terms := map[string]string{}
+	for _, field := range strings.Fields(q) {
+		if i := strings.Index(field, ":"); i > 0 {
+			terms[strings.ToLower(field[:i])] = field[i+1:]
+		}
+	}
+	return terms
+}
+
+// pageBounds reads the offset (or cursor) and limit of a listing, answering with an error if they are invalid.
+func pageBounds(w http.ResponseWriter, query url.Values, offsetParam string) (offset int, limit int, ok bool) {
+	limit = defaultLimit
+	var err error
+	if value := query.Get(offsetParam); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidInput, "Invalid "+offsetParam+": "+value)
+			return 0, 0, false
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidInput, "Invalid limit: "+value)
+			return 0, 0, false
+		}
+	}
+	return offset, limit, true
+}
+
+// pageOf returns the bounds of the page of a list of total items, along with its ResultInfo.
+func pageOf(total int, offset int, limit int) ([2]int, ultradns.ResultInfo) {
+	start := minInt(offset, total)
+	end := minInt(start+limit, total)
+	return [2]int{start, end}, ultradns.ResultInfo{TotalCount: total, Offset: start, ReturnedCount: end - start}
+}
+
+// queryInfo echoes the query parameters of a listing.
+func queryInfo(query url.Values, limit int) ultradns.QueryInfo {
+	return ultradns.QueryInfo{
+		Q:       query.Get("q"),
+		Sort:    query.Get("sort"),
+		Reverse: query.Get("reverse") == "true",
+		Limit:   limit,
+	}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}